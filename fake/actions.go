@@ -0,0 +1,434 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+// Action is an API call made through the typed or the dynamic client of the fake cluster
+type Action struct {
+	Verb        string
+	Resource    schema.GroupVersionResource
+	Subresource string
+	Namespace   string
+	Name        string
+	// PatchType is set for patch calls only
+	PatchType types.PatchType
+	// Body is the JSON encoded object for create and update calls or the raw patch for patch calls
+	Body []byte
+}
+
+func (a Action) String() string {
+	res := a.Resource.Resource
+	if a.Subresource != "" {
+		res += "/" + a.Subresource
+	}
+
+	if a.Resource.Group != "" {
+		res += "." + a.Resource.Group
+	}
+
+	s := a.Verb + " " + res
+
+	if a.Namespace != "" || a.Name != "" {
+		s += " " + strings.TrimPrefix(a.Namespace+"/"+a.Name, "/")
+	}
+
+	if a.PatchType != "" {
+		s += " (" + string(a.PatchType) + ")"
+	}
+
+	return s
+}
+
+// ActionList is a chronologically ordered list of recorded actions
+type ActionList []Action
+
+// Filter returns actions matching all filters
+func (l ActionList) Filter(filters ...ActionFilter) ActionList {
+	var res ActionList
+
+	for _, a := range l {
+		if matchAll(a, filters) {
+			res = append(res, a)
+		}
+	}
+
+	return res
+}
+
+// Contains is true if any action matches m, a shortcut for non-gomega assertions
+func (l ActionList) Contains(m *ActionMatcher) bool {
+	return l.indexOf(m, 0) >= 0
+}
+
+func (l ActionList) indexOf(m *ActionMatcher, from int) int {
+	for i := from; i < len(l); i++ {
+		if m.matches(l[i]) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (l ActionList) String() string {
+	if len(l) == 0 {
+		return "no actions recorded"
+	}
+
+	lines := make([]string, 0, len(l))
+	for i, a := range l {
+		lines = append(lines, fmt.Sprintf("  %d: %s", i, a))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ActionFilter selects actions
+type ActionFilter func(a Action) bool
+
+// WithVerb selects actions with one of verbs: get, list, watch, create, update, patch, delete, deletecollection
+func WithVerb(verbs ...string) ActionFilter {
+	return func(a Action) bool {
+		for _, verb := range verbs {
+			if a.Verb == verb {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// WithResource selects actions by plural resource name, a subresource can be specified as "deployments/status"
+func WithResource(resource string) ActionFilter {
+	name, sub, _ := strings.Cut(resource, "/")
+
+	return func(a Action) bool {
+		return a.Resource.Resource == name && a.Subresource == sub
+	}
+}
+
+// WithNamespace selects actions made in the namespace
+func WithNamespace(ns string) ActionFilter {
+	return func(a Action) bool {
+		return a.Namespace == ns
+	}
+}
+
+// WithName selects actions on the object with the name
+func WithName(name string) ActionFilter {
+	return func(a Action) bool {
+		return a.Name == name
+	}
+}
+
+// Mutating selects actions that change the cluster state
+func Mutating() ActionFilter {
+	return WithVerb("create", "update", "patch", "delete", "deletecollection")
+}
+
+func matchAll(a Action, filters []ActionFilter) bool {
+	for _, filter := range filters {
+		if !filter(a) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Actions returns API calls made through the cluster clients in order, optionally filtered
+func (fc *Cluster) Actions(filters ...ActionFilter) ActionList {
+	return fc.actions.list().Filter(filters...)
+}
+
+// ClearActions drops all recorded API calls
+func (fc *Cluster) ClearActions() {
+	fc.actions.clear()
+}
+
+type actionLog struct {
+	mu      sync.Mutex
+	actions ActionList
+}
+
+func (l *actionLog) list() ActionList {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	res := make(ActionList, len(l.actions))
+	copy(res, l.actions)
+
+	return res
+}
+
+func (l *actionLog) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.actions = nil
+}
+
+func (l *actionLog) record(action testing.Action) {
+	a := Action{
+		Verb:        action.GetVerb(),
+		Resource:    action.GetResource(),
+		Subresource: action.GetSubresource(),
+		Namespace:   action.GetNamespace(),
+	}
+
+	switch act := action.(type) {
+	case testing.PatchAction:
+		a.Name = act.GetName()
+		a.PatchType = act.GetPatchType()
+		a.Body = act.GetPatch()
+	case interface{ GetName() string }:
+		a.Name = act.GetName()
+	case interface{ GetObject() runtime.Object }:
+		obj := act.GetObject()
+		if accessor, err := meta.Accessor(obj); err == nil {
+			a.Name = accessor.GetName()
+		}
+
+		a.Body, _ = json.Marshal(obj)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.actions = append(l.actions, a)
+}
+
+// install puts recording reactors at the beginning of the client reaction chains
+func (l *actionLog) install(f *testing.Fake) {
+	f.PrependReactor("*", "*", func(action testing.Action) (bool, runtime.Object, error) {
+		l.record(action)
+		return false, nil, nil
+	})
+	f.PrependWatchReactor("*", func(action testing.Action) (bool, watch.Interface, error) {
+		l.record(action)
+		return false, nil, nil
+	})
+}
+
+// ActionMatcher matches recorded actions. It implements gomega's GomegaMatcher interface
+// and accepts *Cluster, ActionList or []Action as an actual value:
+//
+//	Expect(cluster).To(fake.HaveCreated("pods", "default", "foo"))
+//
+// Use ActionList.Contains for other assertion libraries.
+type ActionMatcher struct {
+	description string
+	filters     []ActionFilter
+	body        interface{}
+	bodyErr     error
+}
+
+// HaveAction matches an action selected by all filters
+func HaveAction(filters ...ActionFilter) *ActionMatcher {
+	return &ActionMatcher{description: "action", filters: filters}
+}
+
+// HaveCreated matches a create call of the resource object
+func HaveCreated(resource, namespace, name string) *ActionMatcher {
+	return haveVerb("create", resource, namespace, name)
+}
+
+// HaveUpdated matches an update call of the resource object
+func HaveUpdated(resource, namespace, name string) *ActionMatcher {
+	return haveVerb("update", resource, namespace, name)
+}
+
+// HaveDeleted matches a delete call of the resource object
+func HaveDeleted(resource, namespace, name string) *ActionMatcher {
+	return haveVerb("delete", resource, namespace, name)
+}
+
+// HavePatched matches a patch call of the resource object. If subset is not empty,
+// the patch must contain it, see ActionMatcher.WithBody.
+func HavePatched(resource, namespace, name, subset string) *ActionMatcher {
+	m := haveVerb("patch", resource, namespace, name)
+	if subset != "" {
+		m = m.WithBody(subset)
+	}
+
+	return m
+}
+
+func haveVerb(verb, resource, namespace, name string) *ActionMatcher {
+	return &ActionMatcher{
+		description: fmt.Sprintf("%s %s %s", verb, resource, strings.TrimPrefix(namespace+"/"+name, "/")),
+		filters:     []ActionFilter{WithVerb(verb), WithResource(resource), WithNamespace(namespace), WithName(name)},
+	}
+}
+
+// WithBody additionally requires the action body to contain subset. The subset is a JSON or YAML document:
+// objects match if all subset keys match, arrays match element by element, other values must be equal.
+func (m *ActionMatcher) WithBody(subset string) *ActionMatcher {
+	res := *m
+	res.description = m.description + " with body " + subset
+	res.bodyErr = yaml.Unmarshal([]byte(subset), &res.body)
+
+	return &res
+}
+
+func (m *ActionMatcher) matches(a Action) bool {
+	if !matchAll(a, m.filters) {
+		return false
+	}
+
+	if m.body == nil {
+		return true
+	}
+
+	var body interface{}
+	if err := yaml.Unmarshal(a.Body, &body); err != nil {
+		return false
+	}
+
+	return isSubset(m.body, body)
+}
+
+// Match implements gomega's GomegaMatcher interface
+func (m *ActionMatcher) Match(actual interface{}) (bool, error) {
+	if m.bodyErr != nil {
+		return false, fmt.Errorf("invalid body subset: %v", m.bodyErr)
+	}
+
+	actions, err := toActionList(actual)
+	if err != nil {
+		return false, err
+	}
+
+	return actions.Contains(m), nil
+}
+
+// FailureMessage implements gomega's GomegaMatcher interface
+func (m *ActionMatcher) FailureMessage(actual interface{}) string {
+	actions, _ := toActionList(actual)
+	return fmt.Sprintf("Expected %s among recorded actions:\n%s", m.description, actions)
+}
+
+// NegatedFailureMessage implements gomega's GomegaMatcher interface
+func (m *ActionMatcher) NegatedFailureMessage(actual interface{}) string {
+	actions, _ := toActionList(actual)
+	return fmt.Sprintf("Expected no %s among recorded actions:\n%s", m.description, actions)
+}
+
+// ActionSequenceMatcher matches actions that happened in the given order, other actions may
+// happen in between. It implements gomega's GomegaMatcher interface.
+type ActionSequenceMatcher struct {
+	matchers []*ActionMatcher
+}
+
+// HaveActionsInOrder matches recorded actions containing all matchers in order
+func HaveActionsInOrder(matchers ...*ActionMatcher) *ActionSequenceMatcher {
+	return &ActionSequenceMatcher{matchers: matchers}
+}
+
+// Match implements gomega's GomegaMatcher interface
+func (m *ActionSequenceMatcher) Match(actual interface{}) (bool, error) {
+	actions, err := toActionList(actual)
+	if err != nil {
+		return false, err
+	}
+
+	idx := 0
+
+	for _, matcher := range m.matchers {
+		if matcher.bodyErr != nil {
+			return false, fmt.Errorf("invalid body subset: %v", matcher.bodyErr)
+		}
+
+		found := actions.indexOf(matcher, idx)
+		if found < 0 {
+			return false, nil
+		}
+
+		idx = found + 1
+	}
+
+	return true, nil
+}
+
+// FailureMessage implements gomega's GomegaMatcher interface
+func (m *ActionSequenceMatcher) FailureMessage(actual interface{}) string {
+	actions, _ := toActionList(actual)
+	return fmt.Sprintf("Expected actions in order:\n%s\namong recorded actions:\n%s", m.describe(), actions)
+}
+
+// NegatedFailureMessage implements gomega's GomegaMatcher interface
+func (m *ActionSequenceMatcher) NegatedFailureMessage(actual interface{}) string {
+	actions, _ := toActionList(actual)
+	return fmt.Sprintf("Expected no actions in order:\n%s\namong recorded actions:\n%s", m.describe(), actions)
+}
+
+func (m *ActionSequenceMatcher) describe() string {
+	lines := make([]string, 0, len(m.matchers))
+	for _, matcher := range m.matchers {
+		lines = append(lines, "  "+matcher.description)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func toActionList(actual interface{}) (ActionList, error) {
+	switch v := actual.(type) {
+	case *Cluster:
+		return v.Actions(), nil
+	case ActionList:
+		return v, nil
+	case []Action:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("expected *Cluster, ActionList or []Action, got %T", actual)
+	}
+}
+
+// isSubset is true if all fields of expected are present in actual with the same values
+func isSubset(expected, actual interface{}) bool {
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		act, ok := actual.(map[string]interface{})
+		if !ok {
+			return false
+		}
+
+		for k, v := range exp {
+			av, ok := act[k]
+			if !ok || !isSubset(v, av) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		act, ok := actual.([]interface{})
+		if !ok || len(act) != len(exp) {
+			return false
+		}
+
+		for i := range exp {
+			if !isSubset(exp[i], act[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return reflect.DeepEqual(expected, actual)
+	}
+}
@@ -0,0 +1,65 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+func TestActions(t *testing.T) {
+	f := NewFakeCluster("")
+
+	f.CreateSimpleNamespaced("default", "ConfigMap", "foo")
+
+	cms := f.Client.Dynamic().Resource(configMapsGVR).Namespace("default")
+	_, err := cms.Patch(context.TODO(), "foo", types.MergePatchType, []byte(`{"data":{"key":"value","other":"1"}}`), v1.PatchOptions{})
+	require.NoError(t, err)
+
+	err = f.Update("default", manifest.New("v1", "ConfigMap", "foo"))
+	require.NoError(t, err)
+
+	_, err = cms.List(context.TODO(), v1.ListOptions{})
+	require.NoError(t, err)
+
+	f.DeleteSimpleNamespaced("default", "ConfigMap", "foo")
+
+	t.Run("gomega matchers", func(t *testing.T) {
+		g := NewWithT(t)
+
+		g.Expect(f).To(HaveCreated("namespaces", "", "default"))
+		g.Expect(f).To(HaveCreated("configmaps", "default", "foo"))
+		g.Expect(f).To(HavePatched("configmaps", "default", "foo", "data: {key: value}"))
+		g.Expect(f).NotTo(HavePatched("configmaps", "default", "foo", `{"data":{"key":"other"}}`))
+		g.Expect(f).To(HaveUpdated("configmaps", "default", "foo"))
+		g.Expect(f).To(HaveDeleted("configmaps", "default", "foo"))
+		g.Expect(f).To(HaveActionsInOrder(
+			HaveCreated("configmaps", "default", "foo"),
+			HavePatched("configmaps", "default", "foo", ""),
+			HaveDeleted("configmaps", "default", "foo"),
+		))
+		g.Expect(f).NotTo(HaveActionsInOrder(
+			HaveDeleted("configmaps", "default", "foo"),
+			HaveCreated("configmaps", "default", "foo"),
+		))
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		mutating := f.Actions(Mutating(), WithResource("configmaps"))
+		require.Len(t, mutating, 4)
+		require.Equal(t, "patch configmaps default/foo (application/merge-patch+json)", mutating[1].String())
+
+		require.True(t, f.Actions().Contains(HaveAction(WithVerb("list"), WithNamespace("default"))))
+		require.Len(t, f.Actions(WithName("foo"), WithVerb("get")), 0)
+	})
+
+	t.Run("clear", func(t *testing.T) {
+		f.ClearActions()
+		require.Empty(t, f.Actions())
+	})
+}
@@ -0,0 +1,304 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// AdmissionRequest is passed to admission hooks, it mirrors AdmissionRequest of admission/v1
+type AdmissionRequest struct {
+	UID         types.UID
+	Kind        schema.GroupVersionKind
+	Resource    schema.GroupVersionResource
+	SubResource string
+	Name        string
+	Namespace   string
+	Operation   admissionv1.Operation
+	// Object is the new object, it is nil for DELETE
+	Object *unstructured.Unstructured
+	// OldObject is the stored object, it is nil for CREATE
+	OldObject *unstructured.Unstructured
+}
+
+// AdmissionResponse is returned by admission hooks, a nil response allows the request
+type AdmissionResponse struct {
+	Allowed bool
+	// Message and Code are returned to the client if the request is denied, Code is 403 by default
+	Message string
+	Code    int32
+	// Patch is a JSON patch (RFC 6902) for the object, it is ignored for validating hooks
+	Patch []byte
+}
+
+// Allowed allows the request
+func Allowed() *AdmissionResponse {
+	return &AdmissionResponse{Allowed: true}
+}
+
+// Denied denies the request with the message
+func Denied(message string) *AdmissionResponse {
+	return &AdmissionResponse{Message: message}
+}
+
+// Patched allows the request and changes the object with the JSON patch
+func Patched(patch string) *AdmissionResponse {
+	return &AdmissionResponse{Allowed: true, Patch: []byte(patch)}
+}
+
+// AdmissionHook is a Go implementation of an admission webhook. Mutating hooks can change
+// req.Object in place or return a JSON patch, changes made by validating hooks are dropped.
+// Hooks are called while the fake client handles the request and must not call the cluster client.
+type AdmissionHook func(req *AdmissionRequest) *AdmissionResponse
+
+// AdmissionRule selects requests passed to a hook, as rules of webhook configurations do
+type AdmissionRule struct {
+	// APIGroups are matched groups, empty or "*" matches all groups
+	APIGroups []string
+	// Resources are plural resource names, a subresource can be specified as "deployments/status".
+	// "*" matches all resources, "*/*" also matches all subresources. Empty matches all resources.
+	Resources []string
+	// Operations are matched operations, empty or "*" matches all operations
+	Operations []admissionv1.Operation
+}
+
+func (r AdmissionRule) matches(req *AdmissionRequest) bool {
+	return matchRuleValues(r.APIGroups, req.Resource.Group) &&
+		r.matchesOperation(req.Operation) &&
+		r.matchesResource(req.Resource.Resource, req.SubResource)
+}
+
+func (r AdmissionRule) matchesOperation(op admissionv1.Operation) bool {
+	if len(r.Operations) == 0 {
+		return true
+	}
+
+	for _, o := range r.Operations {
+		if o == "*" || o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r AdmissionRule) matchesResource(resource, subresource string) bool {
+	resources := r.Resources
+	if len(resources) == 0 {
+		resources = []string{"*"}
+	}
+
+	for _, res := range resources {
+		name, sub, _ := strings.Cut(res, "/")
+		if (name == "*" || name == resource) && (sub == "*" || sub == subresource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchRuleValues(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+
+	for _, v := range values {
+		if v == "*" || v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+type admissionHook struct {
+	name string
+	rule AdmissionRule
+	hook AdmissionHook
+}
+
+// RegisterMutatingHook adds a mutating admission hook. As in apiserver, all mutating hooks
+// are called before validating ones, hooks of the same type are called in registration order.
+func (fc *Cluster) RegisterMutatingHook(name string, rule AdmissionRule, hook AdmissionHook) {
+	fc.store.mu.Lock()
+	defer fc.store.mu.Unlock()
+
+	fc.mutatingHooks = append(fc.mutatingHooks, admissionHook{name: name, rule: rule, hook: hook})
+}
+
+// RegisterValidatingHook adds a validating admission hook, see RegisterMutatingHook
+func (fc *Cluster) RegisterValidatingHook(name string, rule AdmissionRule, hook AdmissionHook) {
+	fc.store.mu.Lock()
+	defer fc.store.mu.Unlock()
+
+	fc.validatingHooks = append(fc.validatingHooks, admissionHook{name: name, rule: rule, hook: hook})
+}
+
+// admit runs admission hooks for the request, obj is replaced with the object changed by mutating hooks
+func (fc *Cluster) admit(op admissionv1.Operation, gvr schema.GroupVersionResource, subresource, ns string, obj, old runtime.Object) error {
+	if len(fc.mutatingHooks) == 0 && len(fc.validatingHooks) == 0 {
+		return nil
+	}
+
+	req := &AdmissionRequest{
+		UID:         uuid.NewUUID(),
+		Resource:    gvr,
+		SubResource: subresource,
+		Namespace:   ns,
+		Operation:   op,
+	}
+
+	var err error
+
+	if old != nil {
+		req.OldObject, err = toUnstructured(old)
+		if err != nil {
+			return err
+		}
+
+		req.Name = req.OldObject.GetName()
+		req.Kind = req.OldObject.GroupVersionKind()
+	}
+
+	if obj != nil {
+		req.Object, err = toUnstructured(obj)
+		if err != nil {
+			return err
+		}
+
+		req.Name = req.Object.GetName()
+		req.Kind = req.Object.GroupVersionKind()
+	}
+
+	// objects of the typed clientset have no apiVersion and kind
+	if req.Kind.Empty() {
+		req.Kind, _ = fc.kindFor(gvr)
+	}
+
+	for _, u := range []*unstructured.Unstructured{req.OldObject, req.Object} {
+		if u != nil && u.GetKind() == "" {
+			u.SetGroupVersionKind(req.Kind)
+		}
+	}
+
+	for _, h := range fc.mutatingHooks {
+		if !h.rule.matches(req) {
+			continue
+		}
+
+		resp := h.hook(req)
+		if err := resp.err(h.name); err != nil {
+			return err
+		}
+
+		if resp != nil && len(resp.Patch) > 0 && req.Object != nil {
+			req.Object, err = applyJSONPatch(req.Object, resp.Patch)
+			if err != nil {
+				return apierrors.NewInternalError(fmt.Errorf("admission webhook %q returned an invalid patch: %v", h.name, err))
+			}
+		}
+	}
+
+	for _, h := range fc.validatingHooks {
+		if !h.rule.matches(req) {
+			continue
+		}
+
+		if err := h.hook(req.deepCopy()).err(h.name); err != nil {
+			return err
+		}
+	}
+
+	if obj == nil {
+		return nil
+	}
+
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u.Object = req.Object.Object
+		return nil
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(req.Object.Object, obj)
+}
+
+func (r *AdmissionRequest) deepCopy() *AdmissionRequest {
+	c := *r
+
+	if r.Object != nil {
+		c.Object = r.Object.DeepCopy()
+	}
+
+	if r.OldObject != nil {
+		c.OldObject = r.OldObject.DeepCopy()
+	}
+
+	return &c
+}
+
+// err returns the error of a denied request in the same form as apiserver does for webhooks
+func (r *AdmissionResponse) err(hookName string) error {
+	if r == nil || r.Allowed {
+		return nil
+	}
+
+	code := r.Code
+	if code == 0 {
+		code = http.StatusForbidden
+	}
+
+	message := fmt.Sprintf("admission webhook %q denied the request", hookName)
+
+	if r.Message != "" {
+		message += ": " + r.Message
+	}
+
+	status := metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    code,
+		Message: message,
+	}
+
+	if code == http.StatusForbidden {
+		status.Reason = metav1.StatusReasonForbidden
+	}
+
+	return &apierrors.StatusError{ErrStatus: status}
+}
+
+func applyJSONPatch(obj *unstructured.Unstructured, patch []byte) (*unstructured.Unstructured, error) {
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err = decoded.Apply(content)
+	if err != nil {
+		return nil, err
+	}
+
+	patched := &unstructured.Unstructured{}
+
+	err = patched.UnmarshalJSON(content)
+	if err != nil {
+		return nil, err
+	}
+
+	return patched, nil
+}
@@ -0,0 +1,105 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+func TestAdmissionHooks(t *testing.T) {
+	f := NewFakeCluster("")
+	cms := f.Client.Dynamic().Resource(configMapsGVR).Namespace("default")
+
+	var calls []string
+
+	f.RegisterValidatingHook("deny-forbidden", AdmissionRule{Resources: []string{"configmaps"}}, func(req *AdmissionRequest) *AdmissionResponse {
+		calls = append(calls, "validate "+string(req.Operation))
+
+		if req.Object != nil && req.Object.GetLabels()["forbidden"] == "true" {
+			return Denied("forbidden label")
+		}
+
+		return Allowed()
+	})
+	f.RegisterMutatingHook("add-owner", AdmissionRule{Operations: []admissionv1.Operation{admissionv1.Create}}, func(req *AdmissionRequest) *AdmissionResponse {
+		calls = append(calls, "mutate "+string(req.Operation))
+		req.Object.SetLabels(map[string]string{"owner": "team"})
+
+		return nil
+	})
+	f.RegisterMutatingHook("add-data", AdmissionRule{}, func(req *AdmissionRequest) *AdmissionResponse {
+		if req.Operation == admissionv1.Delete {
+			return nil
+		}
+
+		return Patched(`[{"op":"add","path":"/data","value":{"patched":"true"}}]`)
+	})
+
+	t.Run("mutating hooks run before validating", func(t *testing.T) {
+		created, err := cms.Create(context.TODO(), manifest.New("v1", "ConfigMap", "foo").Unstructured(), v1.CreateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"owner": "team"}, created.GetLabels())
+		require.Equal(t, map[string]interface{}{"patched": "true"}, created.Object["data"])
+		require.Equal(t, []string{"mutate CREATE", "validate CREATE"}, calls)
+	})
+
+	t.Run("denied update", func(t *testing.T) {
+		_, err := cms.Patch(context.TODO(), "foo", types.MergePatchType, []byte(`{"metadata":{"labels":{"forbidden":"true"}}}`), v1.PatchOptions{})
+		require.True(t, apierrors.IsForbidden(err))
+		require.Contains(t, err.Error(), `admission webhook "deny-forbidden" denied the request: forbidden label`)
+
+		stored, err := cms.Get(context.TODO(), "foo", v1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"owner": "team"}, stored.GetLabels())
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		calls = nil
+
+		require.NoError(t, cms.Delete(context.TODO(), "foo", v1.DeleteOptions{}))
+		require.Equal(t, []string{"validate DELETE"}, calls)
+	})
+}
+
+func TestAdmissionCreate(t *testing.T) {
+	f := NewFakeCluster("")
+
+	var requests []*AdmissionRequest
+
+	f.RegisterValidatingHook("record", AdmissionRule{Resources: []string{"configmaps"}}, func(req *AdmissionRequest) *AdmissionResponse {
+		requests = append(requests, req)
+		return Allowed()
+	})
+
+	created, err := f.Client.CoreV1().ConfigMaps("default").Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{GenerateName: "cm-"},
+	}, v1.CreateOptions{})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+
+	req := requests[0]
+	require.Equal(t, created.Name, req.Name)
+	require.Equal(t, created.Name, req.Object.GetName())
+	require.Equal(t, created.UID, req.Object.GetUID())
+	require.Equal(t, schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, req.Kind)
+	require.Equal(t, "ConfigMap", req.Object.GetKind())
+}
+
+func TestAdmissionRuleMatches(t *testing.T) {
+	req := &AdmissionRequest{Resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, SubResource: "status", Operation: admissionv1.Update}
+
+	require.False(t, AdmissionRule{}.matches(req))
+	require.True(t, AdmissionRule{Resources: []string{"*/*"}}.matches(req))
+	require.True(t, AdmissionRule{Resources: []string{"deployments/status"}, APIGroups: []string{"apps"}}.matches(req))
+	require.False(t, AdmissionRule{Resources: []string{"deployments/status"}, APIGroups: []string{""}}.matches(req))
+	require.False(t, AdmissionRule{Resources: []string{"*/*"}, Operations: []admissionv1.Operation{admissionv1.Create}}.matches(req))
+}
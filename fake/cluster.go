@@ -6,21 +6,47 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/apimachinery/pkg/watch"
 	fakediscovery "k8s.io/client-go/discovery/fake"
+	fakedynamic "k8s.io/client-go/dynamic/fake"
+	fakekubernetes "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/testing"
 
 	klient "github.com/flant/kube-client/client"
 	"github.com/flant/kube-client/manifest"
 )
 
+// Cluster is a fake cluster for tests. Calls of the typed and the dynamic client get the same apiserver
+// behavior, e.g. server-populated metadata, conflicts and admission hooks, but the clients keep separate objects.
 type Cluster struct {
 	Client *klient.Client
 
 	Discovery *fakediscovery.FakeDiscovery
 	gvrList   map[schema.GroupVersionResource]string
+	// tracker is the object store of the first dynamic client. It is kept
+	// across dynamic client reloads, so registering a CRD doesn't drop objects.
+	tracker testing.ObjectTracker
+	// typedTracker is the object store of the typed clientset, e.g. for namespaces from CreateNs
+	typedTracker testing.ObjectTracker
+	store        *store
+	typedStore   *store
+	actions      *actionLog
+	// selectableFields holds field selector labels of custom resources
+	selectableFields map[schema.GroupVersionResource][]string
+	// crdSubresources holds status and scale subresources of custom resources
+	crdSubresources map[schema.GroupVersionResource]subresources
+	// crdVersions holds storage versions and conversions of custom resources
+	crdVersions map[schema.GroupResource]*crdVersions
+	// mutatingHooks and validatingHooks are admission hooks called by the store
+	mutatingHooks   []admissionHook
+	validatingHooks []admissionHook
 }
 
 func NewFakeCluster(ver ClusterVersion) *Cluster {
@@ -44,7 +70,11 @@ func NewFakeCluster(ver ClusterVersion) *Cluster {
 	}
 
 	fc := &Cluster{
-		gvrList: gvrToListKind,
+		gvrList:          gvrToListKind,
+		actions:          &actionLog{},
+		selectableFields: make(map[schema.GroupVersionResource][]string),
+		crdSubresources:  make(map[schema.GroupVersionResource]subresources),
+		crdVersions:      make(map[schema.GroupResource]*crdVersions),
 	}
 	fc.Client = klient.NewFake(gvrToListKind)
 
@@ -58,11 +88,82 @@ func NewFakeCluster(ver ClusterVersion) *Cluster {
 	fc.Discovery.FakedServerVersion = &version.Info{GitCommit: ver.String(), Major: ver.Major(), Minor: ver.Minor()}
 	fc.Discovery.Resources = cres
 
+	fc.tracker = fc.dynamicClient().Tracker()
+	fc.store = &store{ObjectTracker: fc.tracker, fc: fc, storeState: &storeState{}}
+
+	typed, ok := fc.Client.Interface.(*fakekubernetes.Clientset)
+	if !ok {
+		panic("couldn't convert Interface to *fake.Clientset")
+	}
+
+	fc.typedTracker = typed.Tracker()
+	fc.typedStore = &store{ObjectTracker: fc.typedTracker, fc: fc, scheme: clientgoscheme.Scheme, storeState: fc.store.storeState}
+	fc.installStoreReactors(&typed.Fake, fc.typedStore)
+	fc.actions.install(&typed.Fake)
+	fc.installReactors()
+
 	return fc
 }
 
+func (fc *Cluster) dynamicClient() *fakedynamic.FakeDynamicClient {
+	dc, ok := fc.Client.Dynamic().(*fakedynamic.FakeDynamicClient)
+	if !ok {
+		panic("couldn't convert Dynamic() to *FakeDynamicClient")
+	}
+
+	return dc
+}
+
 func (fc *Cluster) reloadDynamicClient() {
 	fc.Client.ReloadDynamic(fc.gvrList)
+	fc.installReactors()
+}
+
+// installReactors routes the dynamic client to the cluster store
+func (fc *Cluster) installReactors() {
+	dc := fc.dynamicClient()
+	fc.installStoreReactors(&dc.Fake, fc.store)
+	fc.actions.install(&dc.Fake)
+}
+
+// installStoreReactors routes calls of the fake client to the store
+func (fc *Cluster) installStoreReactors(f *testing.Fake, s *store) {
+	f.PrependReactor("*", "*", func(action testing.Action) (bool, runtime.Object, error) {
+		tracker, err := fc.trackerFor(s, action.GetResource(), action.GetSubresource())
+		if err != nil {
+			return true, nil, err
+		}
+
+		return testing.ObjectReaction(tracker)(action)
+	})
+	f.PrependWatchReactor("*", func(action testing.Action) (bool, watch.Interface, error) {
+		var opts metav1.ListOptions
+		if wa, ok := action.(interface{ GetListOptions() metav1.ListOptions }); ok {
+			opts = wa.GetListOptions()
+		}
+
+		tracker, err := fc.trackerFor(s, action.GetResource(), "")
+		if err != nil {
+			return true, nil, err
+		}
+
+		w, err := tracker.Watch(action.GetResource(), action.GetNamespace(), opts)
+		if err != nil {
+			return true, nil, err
+		}
+
+		return true, w, nil
+	})
+}
+
+// trackerFor returns the view of the store serving the resource version and subresource
+func (fc *Cluster) trackerFor(s *store, gvr schema.GroupVersionResource, subresource string) (testing.ObjectTracker, error) {
+	tracker, err := fc.subresourceTracker(s, gvr, subresource)
+	if err != nil {
+		return nil, err
+	}
+
+	return fc.versionTracker(gvr, subresource, tracker), nil
 }
 
 func (fc *Cluster) CreateNs(ns string) {
@@ -71,37 +172,110 @@ func (fc *Cluster) CreateNs(ns string) {
 	_, _ = fc.Client.CoreV1().Namespaces().Create(context.TODO(), nsObj, metav1.CreateOptions{})
 }
 
-// RegisterCRD registers custom resources for the cluster
+// RegisterCRD registers custom resources for the cluster. Versions of the same resource
+// registered with several calls share objects, the first registered version is the storage version.
+// A new version of a built-in resource, e.g. apps/v1beta1 deployments, doesn't share objects with other versions.
 func (fc *Cluster) RegisterCRD(group, version, kind string, namespaced bool) {
 	gvk := schema.GroupVersionKind{Group: group, Version: version, Kind: kind}
 	pluralGVR, _ := meta.UnsafeGuessKindToResource(gvk)
+	versioned := fc.hasStorageVersion(pluralGVR.GroupResource())
 
-	if _, ok := fc.gvrList[pluralGVR]; ok {
+	if !fc.addResource(pluralGVR, kind, namespaced) {
 		return
 	}
 
-	fc.gvrList[pluralGVR] = kind + "List"
+	if versioned {
+		fc.setStorageVersion(pluralGVR, false)
+	}
+
 	fc.reloadDynamicClient()
+}
+
+// RegisterCRDDefinition registers all served versions of the custom resource definition.
+// Unlike RegisterCRD, it uses the plural name from the definition and supports
+// field selectors by selectableFields and status and scale subresources of the versions.
+// Objects are stored in the storage version and converted for other versions, see RegisterConversion.
+// Registering the definition again with another storage version converts stored objects to it.
+func (fc *Cluster) RegisterCRDDefinition(crd *apiextensionsv1.CustomResourceDefinition) {
+	namespaced := crd.Spec.Scope == apiextensionsv1.NamespaceScoped
+	versioned := fc.hasStorageVersion(schema.GroupResource{Group: crd.Spec.Group, Resource: crd.Spec.Names.Plural})
+	added := false
 
+	var storage schema.GroupVersionResource
+
+	for _, ver := range crd.Spec.Versions {
+		if !ver.Served {
+			continue
+		}
+
+		gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: ver.Name, Resource: crd.Spec.Names.Plural}
+		if fc.addResource(gvr, crd.Spec.Names.Kind, namespaced) {
+			added = true
+		}
+
+		fields := make([]string, 0, len(ver.SelectableFields))
+		for _, field := range ver.SelectableFields {
+			fields = append(fields, strings.TrimPrefix(field.JSONPath, "."))
+		}
+
+		fc.selectableFields[gvr] = fields
+		fc.crdSubresources[gvr] = crdSubresources(ver.Subresources)
+
+		if ver.Storage || storage.Empty() {
+			storage = gvr
+		}
+	}
+
+	if versioned && !storage.Empty() {
+		fc.setStorageVersion(storage, true)
+	}
+
+	if added {
+		fc.reloadDynamicClient()
+	}
+}
+
+// addResource adds the resource to discovery and to the list kinds, it is false if the resource is already known
+func (fc *Cluster) addResource(gvr schema.GroupVersionResource, kind string, namespaced bool) bool {
+	if _, ok := fc.gvrList[gvr]; ok {
+		return false
+	}
+
+	fc.gvrList[gvr] = kind + "List"
+
+	groupVersion := gvr.GroupVersion().String()
 	newResource := metav1.APIResource{
 		Kind:       kind,
-		Name:       pluralGVR.Resource,
+		Name:       gvr.Resource,
 		Verbs:      metav1.Verbs{"create", "delete", "deletecollection", "get", "list", "patch", "update", "watch"},
-		Group:      group,
-		Version:    version,
+		Group:      gvr.Group,
+		Version:    gvr.Version,
 		Namespaced: namespaced,
 	}
+
 	for _, resource := range fc.Discovery.Resources {
-		if resource.GroupVersion == group+"/"+version {
+		if resource.GroupVersion == groupVersion {
 			resource.APIResources = append(resource.APIResources, newResource)
-			return
+			return true
 		}
 	}
 
 	fc.Discovery.Resources = append(fc.Discovery.Resources, &metav1.APIResourceList{
-		GroupVersion: group + "/" + version,
+		GroupVersion: groupVersion,
 		APIResources: []metav1.APIResource{newResource},
 	})
+
+	return true
+}
+
+// kindFor returns the kind of a registered resource
+func (fc *Cluster) kindFor(gvr schema.GroupVersionResource) (schema.GroupVersionKind, bool) {
+	listKind, ok := fc.gvrList[gvr]
+	if !ok {
+		return schema.GroupVersionKind{}, false
+	}
+
+	return gvr.GroupVersion().WithKind(strings.TrimSuffix(listKind, "List")), true
 }
 
 func (fc *Cluster) FindGVR(apiVersion, kind string) (*schema.GroupVersionResource, error) {
@@ -181,6 +355,20 @@ func (fc *Cluster) Update(ns string, m manifest.Manifest) error {
 }
 
 func findGvr(resources []*metav1.APIResourceList, apiVersion, kindOrName string) *schema.GroupVersionResource {
+	apiResource := findAPIResource(resources, apiVersion, kindOrName)
+	if apiResource == nil {
+		return nil
+	}
+
+	return &schema.GroupVersionResource{
+		Resource: apiResource.Name,
+		Group:    apiResource.Group,
+		Version:  apiResource.Version,
+	}
+}
+
+// findAPIResource returns a copy of the matching APIResource with Group and Version taken from its list
+func findAPIResource(resources []*metav1.APIResourceList, apiVersion, kindOrName string) *metav1.APIResource {
 	for _, apiResourceGroup := range resources {
 		if apiVersion != "" && apiResourceGroup.GroupVersion != apiVersion {
 			continue
@@ -191,11 +379,10 @@ func findGvr(resources []*metav1.APIResourceList, apiVersion, kindOrName string)
 				// ignore parse error, because FakeClusterResources should be valid
 				gv, _ := schema.ParseGroupVersion(apiResourceGroup.GroupVersion)
 
-				return &schema.GroupVersionResource{
-					Resource: apiResource.Name,
-					Group:    gv.Group,
-					Version:  gv.Version,
-				}
+				apiResource.Group = gv.Group
+				apiResource.Version = gv.Version
+
+				return &apiResource
 			}
 		}
 	}
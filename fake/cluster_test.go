@@ -7,6 +7,8 @@ import (
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/flant/kube-client/manifest"
 )
 
 func TestRegisterCRD(t *testing.T) {
@@ -52,3 +54,76 @@ func TestRegisterCRD(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestDefaultNamespace(t *testing.T) {
+	f := NewFakeCluster("")
+	f.RegisterCRD("deckhouse.io", "v1", "NodeGroup", false)
+
+	ng := manifest.New("deckhouse.io/v1", "NodeGroup", "worker")
+	ng.SetNamespace("default")
+	require.NoError(t, manifest.DefaultNamespace(ng, "web", f.Client))
+	require.Empty(t, ng.ObjectKey().Namespace)
+
+	offline := manifest.NewStaticResolver(ClusterResources(ClusterVersionV127))
+
+	for _, resolver := range []manifest.ScopeResolver{f.Client, offline} {
+		cm := manifest.New("v1", "ConfigMap", "cm")
+		require.NoError(t, manifest.DefaultNamespace(cm, "web", resolver))
+		require.Equal(t, "ConfigMap/web/cm", cm.ObjectKey().String())
+
+		crb := manifest.New("rbac.authorization.k8s.io/v1", "ClusterRoleBinding", "admin")
+		crb.SetNamespace("web")
+		require.NoError(t, manifest.DefaultNamespace(crb, "web", resolver))
+		require.Equal(t, "ClusterRoleBinding.rbac.authorization.k8s.io/admin", crb.ObjectKey().String())
+	}
+}
+
+func TestMissingInCluster(t *testing.T) {
+	f := NewFakeCluster("")
+	require.NoError(t, f.LoadFixturesFromYAML(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+  namespace: prod
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: view
+`))
+
+	manifests, err := manifest.ListFromYamlDocs(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: web
+  namespace: prod
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+subjects:
+- kind: ServiceAccount
+  name: web
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: prod
+spec:
+  tls:
+  - secretName: web-tls
+`)
+	require.NoError(t, err)
+
+	graph := manifest.BuildGraph(manifests)
+	require.Len(t, graph.Missing(), 3)
+
+	missing, err := graph.MissingInCluster(context.TODO(), f.Client)
+	require.NoError(t, err)
+	require.Len(t, missing, 1)
+	require.Equal(t, "ServiceAccount/prod/web", missing[0].To.String())
+	require.Equal(t, "subjects[0].name", missing[0].Path)
+}
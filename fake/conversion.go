@@ -0,0 +1,304 @@
+package fake
+
+import (
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/testing"
+)
+
+// ConversionFunc converts a custom resource to the apiVersion as a conversion webhook does.
+// The object is a copy and can be changed in place.
+type ConversionFunc func(obj *unstructured.Unstructured, apiVersion string) (*unstructured.Unstructured, error)
+
+// DefaultConversion only rewrites apiVersion, as apiserver does for CRDs with the None conversion strategy
+func DefaultConversion(obj *unstructured.Unstructured, apiVersion string) (*unstructured.Unstructured, error) {
+	obj.SetAPIVersion(apiVersion)
+	return obj, nil
+}
+
+// crdVersions describes how versions of a custom resource are stored
+type crdVersions struct {
+	storage string
+	convert ConversionFunc
+}
+
+// RegisterConversion sets the conversion function for versions of the custom resource,
+// DefaultConversion is used if it is not set.
+func (fc *Cluster) RegisterConversion(gr schema.GroupResource, convert ConversionFunc) {
+	versions, ok := fc.crdVersions[gr]
+	if !ok {
+		versions = &crdVersions{}
+		fc.crdVersions[gr] = versions
+	}
+
+	versions.convert = convert
+}
+
+// setStorageVersion sets the version objects of the custom resource are stored in,
+// the first registered version is used unless force is set. Stored objects are converted
+// to the new storage version, so they are still served by all versions.
+func (fc *Cluster) setStorageVersion(gvr schema.GroupVersionResource, force bool) {
+	versions, ok := fc.crdVersions[gvr.GroupResource()]
+	if !ok {
+		versions = &crdVersions{}
+		fc.crdVersions[gvr.GroupResource()] = versions
+	}
+
+	switch {
+	case versions.storage == "":
+		versions.storage = gvr.Version
+	case force && versions.storage != gvr.Version:
+		err := fc.migrateStorage(gvr.GroupResource().WithVersion(versions.storage), gvr, versions.convert)
+		if err != nil {
+			panic(fmt.Sprintf("couldn't change storage version of %s: %v", gvr.GroupResource(), err))
+		}
+
+		versions.storage = gvr.Version
+	}
+}
+
+// migrateStorage converts objects stored in one version to another as the storage version migrator does
+func (fc *Cluster) migrateStorage(from, to schema.GroupVersionResource, convert ConversionFunc) error {
+	if convert == nil {
+		convert = DefaultConversion
+	}
+
+	objs, err := fc.listStored(fc.tracker, from)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objs {
+		converted, err := convert(obj.DeepCopy(), to.GroupVersion().String())
+		if err != nil {
+			return fmt.Errorf("%s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+		}
+
+		err = fc.tracker.Create(to, converted, obj.GetNamespace())
+		if err != nil {
+			return err
+		}
+
+		err = fc.tracker.Delete(from, obj.GetNamespace(), obj.GetName())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasStorageVersion is false for built-in resources, their versions are stored separately
+func (fc *Cluster) hasStorageVersion(gr schema.GroupResource) bool {
+	if versions, ok := fc.crdVersions[gr]; ok && versions.storage != "" {
+		return true
+	}
+
+	for gvr := range fc.gvrList {
+		if gvr.GroupResource() == gr {
+			return false
+		}
+	}
+
+	// a new custom resource
+	return true
+}
+
+// versionTracker wraps the tracker to serve a version different from the storage version
+func (fc *Cluster) versionTracker(gvr schema.GroupVersionResource, subresource string, tracker testing.ObjectTracker) testing.ObjectTracker {
+	versions, ok := fc.crdVersions[gvr.GroupResource()]
+	if !ok || versions.storage == "" || versions.storage == gvr.Version {
+		return tracker
+	}
+
+	view := &versionView{
+		ObjectTracker: tracker,
+		fc:            fc,
+		storage:       gvr.GroupResource().WithVersion(versions.storage),
+	}
+
+	// Scale objects are the same for all versions
+	if subresource != "scale" {
+		view.convert = versions.convert
+		if view.convert == nil {
+			view.convert = DefaultConversion
+		}
+	}
+
+	return view
+}
+
+// versionView serves a version of a custom resource from objects stored in the storage version
+type versionView struct {
+	testing.ObjectTracker
+	fc      *Cluster
+	storage schema.GroupVersionResource
+	convert ConversionFunc
+}
+
+func (v *versionView) Get(gvr schema.GroupVersionResource, ns, name string, opts ...metav1.GetOptions) (runtime.Object, error) {
+	obj, err := v.ObjectTracker.Get(v.storage, ns, name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.convertObject(obj, gvr.GroupVersion())
+}
+
+func (v *versionView) Create(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.CreateOptions) error {
+	stored, err := v.convertObject(obj, v.storage.GroupVersion())
+	if err != nil {
+		return err
+	}
+
+	err = v.ObjectTracker.Create(v.storage, stored, ns, opts...)
+	if err != nil {
+		return err
+	}
+
+	return v.replace(obj, stored, gvr.GroupVersion())
+}
+
+func (v *versionView) Update(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.UpdateOptions) error {
+	stored, err := v.convertObject(obj, v.storage.GroupVersion())
+	if err != nil {
+		return err
+	}
+
+	err = v.ObjectTracker.Update(v.storage, stored, ns, opts...)
+	if err != nil {
+		return err
+	}
+
+	return v.replace(obj, stored, gvr.GroupVersion())
+}
+
+func (v *versionView) Patch(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.PatchOptions) error {
+	stored, err := v.convertObject(obj, v.storage.GroupVersion())
+	if err != nil {
+		return err
+	}
+
+	err = v.ObjectTracker.Patch(v.storage, stored, ns, opts...)
+	if err != nil {
+		return err
+	}
+
+	return v.replace(obj, stored, gvr.GroupVersion())
+}
+
+func (v *versionView) Apply(gvr schema.GroupVersionResource, applyConfiguration runtime.Object, ns string, opts ...metav1.PatchOptions) error {
+	stored, err := v.convertObject(applyConfiguration, v.storage.GroupVersion())
+	if err != nil {
+		return err
+	}
+
+	return v.ObjectTracker.Apply(v.storage, stored, ns, opts...)
+}
+
+func (v *versionView) Delete(_ schema.GroupVersionResource, ns, name string, opts ...metav1.DeleteOptions) error {
+	return v.ObjectTracker.Delete(v.storage, ns, name, opts...)
+}
+
+func (v *versionView) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string, opts ...metav1.ListOptions) (runtime.Object, error) {
+	storageGVK, ok := v.fc.kindFor(v.storage)
+	if !ok {
+		return nil, fmt.Errorf("storage version %s is not registered", v.storage)
+	}
+
+	list, err := v.ObjectTracker.List(v.storage, storageGVK, ns, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	listAccessor, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &unstructured.UnstructuredList{}
+	res.SetGroupVersionKind(gvr.GroupVersion().WithKind(gvk.Kind + "List"))
+	res.SetResourceVersion(listAccessor.GetResourceVersion())
+
+	for _, item := range items {
+		obj, err := v.convertObject(item, gvr.GroupVersion())
+		if err != nil {
+			return nil, err
+		}
+
+		res.Items = append(res.Items, *obj)
+	}
+
+	return res, nil
+}
+
+func (v *versionView) Watch(gvr schema.GroupVersionResource, ns string, opts ...metav1.ListOptions) (watch.Interface, error) {
+	w, err := v.ObjectTracker.Watch(v.storage, ns, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		if event.Type == watch.Error || event.Type == watch.Bookmark {
+			return event, true
+		}
+
+		obj, err := v.convertObject(event.Object, gvr.GroupVersion())
+		if err != nil {
+			status := apierrors.NewInternalError(err).Status()
+			return watch.Event{Type: watch.Error, Object: &status}, true
+		}
+
+		event.Object = obj
+
+		return event, true
+	}), nil
+}
+
+// convertObject returns a copy of the object in the version
+func (v *versionView) convertObject(obj runtime.Object, gv schema.GroupVersion) (*unstructured.Unstructured, error) {
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.convert == nil || u.GetAPIVersion() == gv.String() {
+		return u, nil
+	}
+
+	converted, err := v.convert(u, gv.String())
+	if err != nil {
+		return nil, apierrors.NewInternalError(fmt.Errorf("conversion of %s %s to %s failed: %v", u.GetKind(), u.GetName(), gv, err))
+	}
+
+	converted.SetAPIVersion(gv.String())
+
+	return converted, nil
+}
+
+// replace sets the object to the stored object converted to the version, so reactors return actual content
+func (v *versionView) replace(obj runtime.Object, stored *unstructured.Unstructured, gv schema.GroupVersion) error {
+	converted, err := v.convertObject(stored, gv)
+	if err != nil {
+		return err
+	}
+
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u.Object = converted.Object
+		return nil
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(converted.Object, obj)
+}
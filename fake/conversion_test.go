@@ -0,0 +1,168 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+func TestStorageVersion(t *testing.T) {
+	f := NewFakeCluster("")
+	f.RegisterCRD("example.com", "v1", "Widget", true)
+	f.RegisterCRD("example.com", "v1beta1", "Widget", true)
+
+	v1Widgets := f.Client.Dynamic().Resource(*f.MustFindGVR("example.com/v1", "Widget")).Namespace("default")
+	v1beta1Widgets := f.Client.Dynamic().Resource(*f.MustFindGVR("example.com/v1beta1", "Widget")).Namespace("default")
+
+	created, err := v1beta1Widgets.Create(context.TODO(), manifest.New("example.com/v1beta1", "Widget", "foo").Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "example.com/v1beta1", created.GetAPIVersion())
+
+	obj, err := v1Widgets.Get(context.TODO(), "foo", v1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "example.com/v1", obj.GetAPIVersion())
+	require.Equal(t, created.GetUID(), obj.GetUID())
+
+	list, err := v1beta1Widgets.List(context.TODO(), v1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "example.com/v1beta1", list.Items[0].GetAPIVersion())
+
+	snap, err := f.Snapshot()
+	require.NoError(t, err)
+	require.Len(t, snap.objects[schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}], 1)
+	require.Empty(t, snap.objects[schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}])
+
+	require.NoError(t, v1beta1Widgets.Delete(context.TODO(), "foo", v1.DeleteOptions{}))
+
+	_, err = v1Widgets.Get(context.TODO(), "foo", v1.GetOptions{})
+	require.Error(t, err)
+}
+
+func TestStorageVersionOfBuiltins(t *testing.T) {
+	f := NewFakeCluster("")
+	deployments := f.Client.Dynamic().Resource(*f.MustFindGVR("apps/v1", "Deployment")).Namespace("default")
+
+	_, err := deployments.Create(context.TODO(), manifest.New("apps/v1", "Deployment", "web").Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+
+	f.RegisterCRD("apps", "v1beta1", "Deployment", true)
+
+	_, err = deployments.Get(context.TODO(), "web", v1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestStorageVersionChange(t *testing.T) {
+	f := NewFakeCluster("")
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Widget", Plural: "widgets"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1beta1", Served: true, Storage: true},
+				{Name: "v1", Served: true},
+			},
+		},
+	}
+	f.RegisterCRDDefinition(crd)
+
+	v1Widgets := f.Client.Dynamic().Resource(*f.MustFindGVR("example.com/v1", "Widget")).Namespace("default")
+
+	created, err := v1Widgets.Create(context.TODO(), manifest.New("example.com/v1", "Widget", "foo").Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+
+	crd.Spec.Versions[0].Storage, crd.Spec.Versions[1].Storage = false, true
+	f.RegisterCRDDefinition(crd)
+
+	obj, err := v1Widgets.Get(context.TODO(), "foo", v1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, created.GetUID(), obj.GetUID())
+
+	snap, err := f.Snapshot()
+	require.NoError(t, err)
+	require.Len(t, snap.objects[schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}], 1)
+	require.Empty(t, snap.objects[schema.GroupVersionResource{Group: "example.com", Version: "v1beta1", Resource: "widgets"}])
+}
+
+func TestConversion(t *testing.T) {
+	f := NewFakeCluster("")
+	f.RegisterCRDDefinition(&apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Pool", Plural: "pools"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true},
+				{Name: "v1", Served: true, Storage: true},
+			},
+		},
+	})
+
+	// v1alpha1 has spec.count, v1 has spec.size
+	f.RegisterConversion(schema.GroupResource{Group: "example.com", Resource: "pools"}, func(obj *unstructured.Unstructured, apiVersion string) (*unstructured.Unstructured, error) {
+		from, to := []string{"spec", "count"}, []string{"spec", "size"}
+		if apiVersion == "example.com/v1alpha1" {
+			from, to = to, from
+		}
+
+		if val, found, _ := unstructured.NestedFieldCopy(obj.Object, from...); found {
+			unstructured.RemoveNestedField(obj.Object, from...)
+			_ = unstructured.SetNestedField(obj.Object, val, to...)
+		}
+
+		return obj, nil
+	})
+
+	v1Pools := f.Client.Dynamic().Resource(*f.MustFindGVR("example.com/v1", "Pool")).Namespace("default")
+	alphaPools := f.Client.Dynamic().Resource(*f.MustFindGVR("example.com/v1alpha1", "Pool")).Namespace("default")
+
+	w, err := alphaPools.Watch(context.TODO(), v1.ListOptions{})
+	require.NoError(t, err)
+
+	defer w.Stop()
+
+	_, err = v1Pools.Create(context.TODO(), manifest.MustFromYAML(`
+apiVersion: example.com/v1
+kind: Pool
+metadata:
+  name: main
+spec:
+  size: 3
+`).Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+
+	select {
+	case event := <-w.ResultChan():
+		require.Equal(t, watch.Added, event.Type)
+
+		obj, ok := event.Object.(*unstructured.Unstructured)
+		require.True(t, ok)
+		require.Equal(t, "example.com/v1alpha1", obj.GetAPIVersion())
+		require.Equal(t, map[string]interface{}{"count": float64(3)}, obj.Object["spec"])
+	case <-time.After(time.Second):
+		t.Fatal("no watch event")
+	}
+
+	obj, err := alphaPools.Get(context.TODO(), "main", v1.GetOptions{})
+	require.NoError(t, err)
+
+	_ = unstructured.SetNestedField(obj.Object, int64(5), "spec", "count")
+
+	_, err = alphaPools.Update(context.TODO(), obj, v1.UpdateOptions{})
+	require.NoError(t, err)
+
+	stored, err := v1Pools.Get(context.TODO(), "main", v1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"size": int64(5)}, stored.Object["spec"])
+	require.Equal(t, int64(2), stored.GetGeneration())
+}
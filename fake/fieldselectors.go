@@ -0,0 +1,195 @@
+package fake
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fieldValue returns a field selector value of the object
+type fieldValue func(obj map[string]interface{}) string
+
+// builtinFieldLabels are field selector labels supported by apiserver for built-in resources
+// in addition to metadata.name and metadata.namespace
+var builtinFieldLabels = map[schema.GroupResource]map[string]fieldValue{
+	{Resource: "pods"}: {
+		"spec.nodeName":            stringField("spec", "nodeName"),
+		"spec.restartPolicy":       stringField("spec", "restartPolicy"),
+		"spec.schedulerName":       stringField("spec", "schedulerName"),
+		"spec.serviceAccountName":  stringField("spec", "serviceAccountName"),
+		"spec.hostNetwork":         boolField("spec", "hostNetwork"),
+		"status.phase":             stringField("status", "phase"),
+		"status.podIP":             stringField("status", "podIP"),
+		"status.nominatedNodeName": stringField("status", "nominatedNodeName"),
+	},
+	{Resource: "events"}: {
+		"involvedObject.kind":            stringField("involvedObject", "kind"),
+		"involvedObject.namespace":       stringField("involvedObject", "namespace"),
+		"involvedObject.name":            stringField("involvedObject", "name"),
+		"involvedObject.uid":             stringField("involvedObject", "uid"),
+		"involvedObject.apiVersion":      stringField("involvedObject", "apiVersion"),
+		"involvedObject.resourceVersion": stringField("involvedObject", "resourceVersion"),
+		"involvedObject.fieldPath":       stringField("involvedObject", "fieldPath"),
+		"reason":                         stringField("reason"),
+		"reportingComponent":             stringField("reportingComponent"),
+		"source":                         stringField("source", "component"),
+		"type":                           stringField("type"),
+	},
+	{Resource: "namespaces"}: {
+		"status.phase": stringField("status", "phase"),
+	},
+	{Resource: "nodes"}: {
+		"spec.unschedulable": boolField("spec", "unschedulable"),
+	},
+	{Resource: "replicationcontrollers"}: {
+		"status.replicas": intField("status", "replicas"),
+	},
+	{Resource: "secrets"}: {
+		"type": stringField("type"),
+	},
+	{Resource: "services"}: {
+		"spec.clusterIP": stringField("spec", "clusterIP"),
+		"spec.type":      stringField("spec", "type"),
+	},
+	{Group: "apps", Resource: "replicasets"}: {
+		"status.replicas": intField("status", "replicas"),
+	},
+	{Group: "batch", Resource: "jobs"}: {
+		"status.successful": intField("status", "successful"),
+	},
+	{Group: "certificates.k8s.io", Resource: "certificatesigningrequests"}: {
+		"spec.signerName": stringField("spec", "signerName"),
+	},
+}
+
+func stringField(path ...string) fieldValue {
+	return func(obj map[string]interface{}) string {
+		val, _, _ := unstructured.NestedFieldNoCopy(obj, path...)
+		if val == nil {
+			return ""
+		}
+
+		return fmt.Sprint(val)
+	}
+}
+
+func boolField(path ...string) fieldValue {
+	return func(obj map[string]interface{}) string {
+		val, _, _ := unstructured.NestedBool(obj, path...)
+		return strconv.FormatBool(val)
+	}
+}
+
+func intField(path ...string) fieldValue {
+	return func(obj map[string]interface{}) string {
+		val, _, _ := unstructured.NestedFieldNoCopy(obj, path...)
+		if val == nil {
+			return "0"
+		}
+
+		return fmt.Sprint(val)
+	}
+}
+
+// fieldLabels returns field selector labels supported for the resource
+func (fc *Cluster) fieldLabels(gvr schema.GroupVersionResource) map[string]fieldValue {
+	res := map[string]fieldValue{
+		"metadata.name": stringField("metadata", "name"),
+	}
+
+	apiResource := findAPIResource(fc.Discovery.Resources, gvr.GroupVersion().String(), gvr.Resource)
+	if apiResource == nil || apiResource.Namespaced {
+		res["metadata.namespace"] = stringField("metadata", "namespace")
+	}
+
+	for label, value := range builtinFieldLabels[gvr.GroupResource()] {
+		res[label] = value
+	}
+
+	for _, label := range fc.selectableFields[gvr] {
+		res[label] = stringField(strings.Split(label, ".")...)
+	}
+
+	return res
+}
+
+// objectFilter matches objects by label and field selectors
+type objectFilter struct {
+	labels      labels.Selector
+	fields      fields.Selector
+	fieldLabels map[string]fieldValue
+}
+
+// newObjectFilter returns nil if list options have no selectors
+func (fc *Cluster) newObjectFilter(gvr schema.GroupVersionResource, opts metav1.ListOptions) (*objectFilter, error) {
+	if opts.LabelSelector == "" && opts.FieldSelector == "" {
+		return nil, nil
+	}
+
+	filter := &objectFilter{
+		labels: labels.Everything(),
+		fields: fields.Everything(),
+	}
+
+	var err error
+
+	if opts.LabelSelector != "" {
+		filter.labels, err = labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	}
+
+	if opts.FieldSelector != "" {
+		filter.fields, err = fields.ParseSelector(opts.FieldSelector)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+
+		filter.fieldLabels = fc.fieldLabels(gvr)
+
+		for _, req := range filter.fields.Requirements() {
+			if _, ok := filter.fieldLabels[req.Field]; !ok {
+				return nil, apierrors.NewBadRequest(fmt.Sprintf("field label not supported: %s", req.Field))
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+func (f *objectFilter) matches(obj runtime.Object) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+
+	if !f.labels.Matches(labels.Set(accessor.GetLabels())) {
+		return false
+	}
+
+	if f.fields.Empty() {
+		return true
+	}
+
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return false
+	}
+
+	set := make(fields.Set, len(f.fieldLabels))
+	for label, value := range f.fieldLabels {
+		set[label] = value(u.Object)
+	}
+
+	return f.fields.Matches(set)
+}
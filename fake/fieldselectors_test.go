@@ -0,0 +1,171 @@
+package fake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var podsGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+func TestFieldSelectorList(t *testing.T) {
+	f := NewFakeCluster("")
+
+	err := f.LoadFixturesFromYAML(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: a
+  labels: {app: web}
+spec:
+  nodeName: node-1
+status:
+  phase: Running
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: b
+  labels: {app: web}
+spec:
+  nodeName: node-2
+---
+apiVersion: v1
+kind: Event
+metadata:
+  name: a.1
+involvedObject:
+  kind: Pod
+  name: a
+type: Warning
+`)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		resource schema.GroupVersionResource
+		opts     v1.ListOptions
+		expected []string
+	}{
+		{"node name", podsGVR, v1.ListOptions{FieldSelector: "spec.nodeName=node-1"}, []string{"a"}},
+		{"phase not equal", podsGVR, v1.ListOptions{FieldSelector: "status.phase!=Running"}, []string{"b"}},
+		{"name and labels", podsGVR, v1.ListOptions{FieldSelector: "metadata.name=b", LabelSelector: "app=web"}, []string{"b"}},
+		{"namespace", podsGVR, v1.ListOptions{FieldSelector: "metadata.namespace=other"}, nil},
+		{"involved object", schema.GroupVersionResource{Version: "v1", Resource: "events"}, v1.ListOptions{FieldSelector: "involvedObject.name=a,type=Warning"}, []string{"a.1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := f.Client.Dynamic().Resource(tt.resource).Namespace("default").List(context.TODO(), tt.opts)
+			require.NoError(t, err)
+
+			var names []string
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
+			}
+
+			require.Equal(t, tt.expected, names)
+		})
+	}
+
+	t.Run("unsupported field", func(t *testing.T) {
+		_, err := f.Client.Dynamic().Resource(podsGVR).List(context.TODO(), v1.ListOptions{FieldSelector: "spec.priority=1"})
+		require.True(t, apierrors.IsBadRequest(err))
+		require.ErrorContains(t, err, "field label not supported: spec.priority")
+	})
+}
+
+func TestFieldSelectorWatch(t *testing.T) {
+	f := NewFakeCluster("")
+
+	f.CreateSimpleNamespaced("default", "Pod", "a")
+
+	w, err := f.Client.Dynamic().Resource(podsGVR).Namespace("default").Watch(context.TODO(), v1.ListOptions{FieldSelector: "spec.nodeName=node-1"})
+	require.NoError(t, err)
+
+	defer w.Stop()
+
+	pods := f.Client.Dynamic().Resource(podsGVR).Namespace("default")
+	patch := func(nodeName string) {
+		_, err := pods.Patch(context.TODO(), "a", types.MergePatchType, []byte(`{"spec":{"nodeName":"`+nodeName+`"}}`), v1.PatchOptions{})
+		require.NoError(t, err)
+	}
+
+	patch("node-1")
+	requireEvent(t, w, watch.Added, "a")
+
+	patch("node-2")
+	requireEvent(t, w, watch.Deleted, "a")
+
+	f.CreateSimpleNamespaced("default", "Pod", "b")
+	patch("node-1")
+	requireEvent(t, w, watch.Added, "a")
+
+	f.DeleteSimpleNamespaced("default", "Pod", "a")
+	requireEvent(t, w, watch.Deleted, "a")
+}
+
+func TestSelectableFields(t *testing.T) {
+	f := NewFakeCluster("")
+
+	f.RegisterCRDDefinition(&apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Shirt", Plural: "shirts"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:             "v1",
+				Served:           true,
+				Storage:          true,
+				SelectableFields: []apiextensionsv1.SelectableField{{JSONPath: ".spec.color"}},
+			}},
+		},
+	})
+
+	err := f.LoadFixturesFromYAML(`
+apiVersion: example.com/v1
+kind: Shirt
+metadata:
+  name: red
+spec:
+  color: red
+---
+apiVersion: example.com/v1
+kind: Shirt
+metadata:
+  name: blue
+spec:
+  color: blue
+`)
+	require.NoError(t, err)
+
+	shirts := f.Client.Dynamic().Resource(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "shirts"})
+
+	list, err := shirts.List(context.TODO(), v1.ListOptions{FieldSelector: "spec.color=blue"})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	require.Equal(t, "blue", list.Items[0].GetName())
+
+	_, err = shirts.List(context.TODO(), v1.ListOptions{FieldSelector: "spec.size=M"})
+	require.ErrorContains(t, err, "field label not supported: spec.size")
+}
+
+func requireEvent(t *testing.T, w watch.Interface, eventType watch.EventType, name string) {
+	t.Helper()
+
+	select {
+	case event := <-w.ResultChan():
+		require.Equal(t, eventType, event.Type)
+		require.Equal(t, name, runtimeObjectKey(event.Object).Name)
+	case <-time.After(time.Second):
+		t.Fatalf("no %s event for %s", eventType, name)
+	}
+}
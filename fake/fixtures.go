@@ -0,0 +1,75 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+// LoadFixtures creates objects from YAML or JSON files. A path can be a single file,
+// a directory, a glob pattern or "-" for stdin, directories are walked recursively
+// in lexical order and only files with .yaml, .yml or .json extension are loaded.
+func (fc *Cluster) LoadFixtures(paths ...string) error {
+	return manifest.WalkFiles(func(doc *manifest.Document) error {
+		if !doc.Manifest.HasBasicFields() {
+			return nil
+		}
+
+		err := fc.createFixture(doc.Manifest)
+		if err != nil {
+			return fmt.Errorf("loading fixture %s: %v", doc.Source, err)
+		}
+
+		return nil
+	}, paths...)
+}
+
+// LoadFixturesFromYAML creates objects from a multi-document YAML string.
+// GVRs are resolved the same way as in FindGVR. Namespaced objects without metadata.namespace
+// go to the "default" namespace, and namespaces are created on demand.
+func (fc *Cluster) LoadFixturesFromYAML(docs string) error {
+	manifests, err := manifest.ListFromYamlDocs(docs)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range manifests {
+		err = fc.createFixture(m)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fc *Cluster) createFixture(m manifest.Manifest) error {
+	apiResource := findAPIResource(fc.Discovery.Resources, m.ApiVersion(), m.Kind())
+	if apiResource == nil {
+		return fmt.Errorf("GVR for %s is not find", m.Kind())
+	}
+
+	gvr := schema.GroupVersionResource{Group: apiResource.Group, Version: apiResource.Version, Resource: apiResource.Name}
+	obj := m.Unstructured()
+
+	ns := ""
+	if apiResource.Namespaced {
+		ns = m.Namespace("default")
+		fc.CreateNs(ns)
+	}
+
+	obj.SetNamespace(ns)
+	// fixtures can be exported from a live cluster, but apiserver rejects creation with resourceVersion
+	obj.SetResourceVersion("")
+
+	_, err := fc.Client.Dynamic().Resource(gvr).Namespace(ns).Create(context.TODO(), obj, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("creating fixture %s: %v", m.Id(), err)
+	}
+
+	return nil
+}
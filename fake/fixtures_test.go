@@ -0,0 +1,187 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+var configMapsGVR = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+func TestLoadFixtures(t *testing.T) {
+	f := NewFakeCluster("")
+
+	err := f.LoadFixtures("testdata/fixtures")
+	require.NoError(t, err)
+
+	cm, err := f.Client.Dynamic().Resource(configMapsGVR).Namespace("app").Get(context.TODO(), "settings", v1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "value", cm.Object["data"].(map[string]interface{})["key"])
+
+	_, err = f.Client.Dynamic().Resource(configMapsGVR).Namespace("default").Get(context.TODO(), "defaults", v1.GetOptions{})
+	require.NoError(t, err)
+
+	_, err = f.Client.CoreV1().Namespaces().Get(context.TODO(), "app", v1.GetOptions{})
+	require.NoError(t, err)
+
+	role, err := f.Client.Dynamic().Resource(*f.MustFindGVR("rbac.authorization.k8s.io/v1", "ClusterRole")).Get(context.TODO(), "reader", v1.GetOptions{})
+	require.NoError(t, err)
+	require.Empty(t, role.GetNamespace())
+
+	err = f.LoadFixturesFromYAML("apiVersion: example.com/v1\nkind: Unknown\nmetadata:\n  name: foo\n")
+	require.ErrorContains(t, err, "is not find")
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	f := NewFakeCluster("")
+
+	err := f.LoadFixturesFromYAML(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+data:
+  key: one
+`)
+	require.NoError(t, err)
+
+	snap, err := f.Snapshot()
+	require.NoError(t, err)
+
+	t.Run("modify state", func(t *testing.T) {
+		err := f.LoadFixturesFromYAML("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: second\n  namespace: other\n")
+		require.NoError(t, err)
+
+		f.RegisterCRD("deckhouse.io", "v1", "NodeGroup", false)
+		err = f.LoadFixturesFromYAML("apiVersion: deckhouse.io/v1\nkind: NodeGroup\nmetadata:\n  name: worker\n")
+		require.NoError(t, err)
+
+		// objects stored before the dynamic client reload are kept
+		_, err = f.Client.Dynamic().Resource(configMapsGVR).Namespace("default").Get(context.TODO(), "first", v1.GetOptions{})
+		require.NoError(t, err)
+
+		f.DeleteSimpleNamespaced("default", "ConfigMap", "first")
+	})
+
+	t.Run("restore state", func(t *testing.T) {
+		err := f.Restore(snap)
+		require.NoError(t, err)
+
+		list, err := f.Client.Dynamic().Resource(configMapsGVR).Namespace("").List(context.TODO(), v1.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, list.Items, 1)
+		require.Equal(t, "first", list.Items[0].GetName())
+
+		ngs, err := f.Client.Dynamic().Resource(*f.MustFindGVR("deckhouse.io/v1", "NodeGroup")).List(context.TODO(), v1.ListOptions{})
+		require.NoError(t, err)
+		require.Empty(t, ngs.Items)
+
+		// namespaces are created with the typed client
+		namespaces, err := f.Client.CoreV1().Namespaces().List(context.TODO(), v1.ListOptions{})
+		require.NoError(t, err)
+		require.Len(t, namespaces.Items, 1)
+		require.Equal(t, "default", namespaces.Items[0].Name)
+	})
+}
+
+func TestDumpYAML(t *testing.T) {
+	f := NewFakeCluster("")
+
+	err := f.LoadFixturesFromYAML(`
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: b
+  name: cm
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  namespace: a
+  name: cm
+data:
+  z: "1"
+  a: "2"
+`)
+	require.NoError(t, err)
+
+	dump, err := f.DumpYAML()
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: v1
+data:
+  a: "2"
+  z: "1"
+kind: ConfigMap
+metadata:
+  generation: 1
+  name: cm
+  namespace: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  generation: 1
+  name: cm
+  namespace: b
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  generation: 1
+  name: a
+spec: {}
+status: {}
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  generation: 1
+  name: b
+spec: {}
+status: {}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  generation: 1
+  name: reader
+`, dump)
+}
+
+func TestDumpRedactedYAML(t *testing.T) {
+	f := NewFakeCluster("")
+
+	err := f.LoadFixturesFromYAML(`
+apiVersion: v1
+kind: Secret
+metadata:
+  namespace: a
+  name: creds
+data:
+  password: c2VjcmV0
+`)
+	require.NoError(t, err)
+
+	dump, err := f.DumpYAML()
+	require.NoError(t, err)
+	require.Contains(t, dump, "password: <redacted>")
+	require.NotContains(t, dump, "c2VjcmV0")
+
+	dump, err = f.DumpRedactedYAML(manifest.Redactor{})
+	require.NoError(t, err)
+	require.Contains(t, dump, "password: <redacted>")
+
+	dump, err = f.DumpRawYAML()
+	require.NoError(t, err)
+	require.Contains(t, dump, "password: c2VjcmV0")
+}
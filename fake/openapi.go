@@ -0,0 +1,29 @@
+package fake
+
+import (
+	"fmt"
+	"io/fs"
+
+	"k8s.io/client-go/openapi"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+// OpenAPIBundle returns an OpenAPI v3 client for the cluster version from a bundle with
+// a directory per minor version, e.g. "v1.27/apis__apps__v1_openapi.json", see manifest.NewOpenAPIBundle.
+// It is used to validate manifests offline with manifest.NewSchemaValidator.
+func OpenAPIBundle(fsys fs.FS, ver ClusterVersion) (openapi.Client, error) {
+	dir := "v" + ver.Major() + "." + ver.Minor()
+
+	_, err := fs.Stat(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no OpenAPI bundle for %s: %w", ver, err)
+	}
+
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.NewOpenAPIBundle(sub), nil
+}
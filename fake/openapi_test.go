@@ -0,0 +1,37 @@
+package fake
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+func TestOpenAPIBundle(t *testing.T) {
+	bundle := fstest.MapFS{
+		"v1.27/api__v1_openapi.json": {Data: []byte(`{"components": {"schemas": {"io.k8s.api.core.v1.ConfigMap": {
+			"type": "object",
+			"x-kubernetes-group-version-kind": [{"group": "", "version": "v1", "kind": "ConfigMap"}],
+			"properties": {"apiVersion": {"type": "string"}, "kind": {"type": "string"}, "metadata": {"type": "object"},
+				"data": {"type": "object", "additionalProperties": {"type": "string"}}}
+		}}}}`)},
+	}
+
+	client, err := OpenAPIBundle(bundle, ClusterVersionV127)
+	require.NoError(t, err)
+
+	cm := manifest.New("v1", "ConfigMap", "cm")
+	require.NoError(t, cm.Set("data.replicas", 1))
+	require.NoError(t, cm.Set("binaryData", map[string]interface{}{}))
+
+	errs, err := manifest.NewSchemaValidator(client).Validate(cm)
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	require.EqualError(t, errs[0], "ConfigMap/cm: binaryData: unknown field")
+	require.EqualError(t, errs[1], "ConfigMap/cm: data.replicas: type mismatch: expected string, got number")
+
+	_, err = OpenAPIBundle(bundle, ClusterVersionV128)
+	require.ErrorContains(t, err, "no OpenAPI bundle for v1.28.0")
+}
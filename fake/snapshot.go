@@ -0,0 +1,315 @@
+package fake
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+// Snapshot is a point-in-time copy of all objects stored in the fake cluster,
+// objects of the dynamic client and of the typed client (e.g. namespaces from CreateNs) are kept apart.
+type Snapshot struct {
+	objects map[schema.GroupVersionResource][]*unstructured.Unstructured
+	typed   map[schema.GroupVersionResource][]*unstructured.Unstructured
+}
+
+// Snapshot returns a deep copy of the current cluster state
+func (fc *Cluster) Snapshot() (*Snapshot, error) {
+	snap := &Snapshot{
+		objects: make(map[schema.GroupVersionResource][]*unstructured.Unstructured),
+		typed:   make(map[schema.GroupVersionResource][]*unstructured.Unstructured),
+	}
+
+	for gvr := range fc.gvrList {
+		objs, err := fc.listStored(fc.tracker, gvr)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(objs) > 0 {
+			snap.objects[gvr] = objs
+		}
+
+		if !fc.isTyped(gvr) {
+			continue
+		}
+
+		objs, err = fc.listStored(fc.typedTracker, gvr)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(objs) > 0 {
+			snap.typed[gvr] = objs
+		}
+	}
+
+	return snap, nil
+}
+
+// Restore brings the cluster state back to the snapshot. Objects are restored in place,
+// so clients and watchers stay valid and receive events for restored changes.
+func (fc *Cluster) Restore(snap *Snapshot) error {
+	for gvr := range fc.gvrList {
+		err := fc.restoreStored(fc.tracker, gvr, snap.objects[gvr], func(obj *unstructured.Unstructured) (runtime.Object, error) {
+			return obj.DeepCopy(), nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if !fc.isTyped(gvr) {
+			continue
+		}
+
+		err = fc.restoreStored(fc.typedTracker, gvr, snap.typed[gvr], func(obj *unstructured.Unstructured) (runtime.Object, error) {
+			typed, err := clientgoscheme.Scheme.New(obj.GroupVersionKind())
+			if err != nil {
+				return nil, err
+			}
+
+			return typed, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, typed)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreStored makes objects of the resource in the tracker equal to the wanted ones,
+// convert returns the object to store, the typed tracker needs typed objects
+func (fc *Cluster) restoreStored(
+	tracker testing.ObjectTracker,
+	gvr schema.GroupVersionResource,
+	objs []*unstructured.Unstructured,
+	convert func(obj *unstructured.Unstructured) (runtime.Object, error),
+) error {
+	current, err := fc.listStored(tracker, gvr)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[types.NamespacedName]*unstructured.Unstructured, len(current))
+	for _, obj := range current {
+		existing[objectKey(obj)] = obj
+	}
+
+	wanted := make(map[types.NamespacedName]struct{}, len(objs))
+
+	for _, obj := range objs {
+		key := objectKey(obj)
+		wanted[key] = struct{}{}
+
+		old, ok := existing[key]
+		if ok && reflect.DeepEqual(old.Object, obj.Object) {
+			continue
+		}
+
+		stored, err := convert(obj)
+		if err != nil {
+			return fmt.Errorf("restoring %s %s: %v", gvr.Resource, key, err)
+		}
+
+		if ok {
+			err = tracker.Update(gvr, stored, obj.GetNamespace())
+		} else {
+			err = tracker.Create(gvr, stored, obj.GetNamespace())
+		}
+
+		if err != nil {
+			return fmt.Errorf("restoring %s %s: %v", gvr.Resource, key, err)
+		}
+	}
+
+	for key := range existing {
+		if _, ok := wanted[key]; ok {
+			continue
+		}
+
+		err = tracker.Delete(gvr, key.Namespace, key.Name)
+		if err != nil {
+			return fmt.Errorf("deleting %s %s: %v", gvr.Resource, key, err)
+		}
+	}
+
+	return nil
+}
+
+// isTyped is true if the typed client can list the resource, e.g. virtual resources like TokenReview have no lists
+func (fc *Cluster) isTyped(gvr schema.GroupVersionResource) bool {
+	gvk, ok := fc.kindFor(gvr)
+
+	return ok && clientgoscheme.Scheme.Recognizes(gvk) && clientgoscheme.Scheme.Recognizes(gvk.GroupVersion().WithKind(gvk.Kind+"List"))
+}
+
+// DumpYAML returns all stored objects as a multi-document YAML. Documents are sorted
+// by group, version, resource, namespace and name, and volatile metadata (uid,
+// resourceVersion and creationTimestamp) is omitted, so the output is stable
+// and can be compared with golden files. Secret data and stringData are redacted by
+// the default manifest.Redactor, use DumpRawYAML for golden files with Secret values.
+func (fc *Cluster) DumpYAML() (string, error) {
+	snap, err := fc.Snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	return snap.YAML()
+}
+
+// DumpRawYAML is DumpYAML without redaction, Secret values are printed as is
+func (fc *Cluster) DumpRawYAML() (string, error) {
+	snap, err := fc.Snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	return snap.RawYAML()
+}
+
+// DumpRedactedYAML is DumpYAML with sensitive values masked by a custom redactor
+func (fc *Cluster) DumpRedactedYAML(r manifest.Redactor) (string, error) {
+	snap, err := fc.Snapshot()
+	if err != nil {
+		return "", err
+	}
+
+	return snap.RedactedYAML(r)
+}
+
+// YAML returns snapshot objects as a sorted multi-document YAML with redacted Secrets, see Cluster.DumpYAML
+func (s *Snapshot) YAML() (string, error) {
+	return s.yaml(&manifest.Redactor{})
+}
+
+// RawYAML is YAML without redaction, see Cluster.DumpRawYAML
+func (s *Snapshot) RawYAML() (string, error) {
+	return s.yaml(nil)
+}
+
+// RedactedYAML is YAML with sensitive values masked by the redactor
+func (s *Snapshot) RedactedYAML(r manifest.Redactor) (string, error) {
+	return s.yaml(&r)
+}
+
+func (s *Snapshot) yaml(r *manifest.Redactor) (string, error) {
+	objects := make(map[schema.GroupVersionResource][]*unstructured.Unstructured, len(s.objects))
+	for _, stored := range []map[schema.GroupVersionResource][]*unstructured.Unstructured{s.objects, s.typed} {
+		for gvr, objs := range stored {
+			objects[gvr] = append(objects[gvr], objs...)
+		}
+	}
+
+	gvrs := make([]schema.GroupVersionResource, 0, len(objects))
+	for gvr, objs := range objects {
+		gvrs = append(gvrs, gvr)
+
+		sort.SliceStable(objs, func(i, j int) bool {
+			if objs[i].GetNamespace() != objs[j].GetNamespace() {
+				return objs[i].GetNamespace() < objs[j].GetNamespace()
+			}
+
+			return objs[i].GetName() < objs[j].GetName()
+		})
+	}
+
+	sort.Slice(gvrs, func(i, j int) bool {
+		if gvrs[i].Group != gvrs[j].Group {
+			return gvrs[i].Group < gvrs[j].Group
+		}
+
+		if gvrs[i].Version != gvrs[j].Version {
+			return gvrs[i].Version < gvrs[j].Version
+		}
+
+		return gvrs[i].Resource < gvrs[j].Resource
+	})
+
+	docs := make([]string, 0, len(gvrs))
+
+	for _, gvr := range gvrs {
+		for _, obj := range objects[gvr] {
+			obj = obj.DeepCopy()
+			obj.SetUID("")
+			obj.SetResourceVersion("")
+			unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+
+			content := obj.Object
+			if r != nil {
+				content = r.Redact(content)
+			}
+
+			doc, err := yaml.Marshal(content)
+			if err != nil {
+				return "", err
+			}
+
+			docs = append(docs, string(doc))
+		}
+	}
+
+	return strings.Join(docs, "---\n"), nil
+}
+
+// listStored returns objects of the resource in the tracker from all namespaces sorted by namespace and name
+func (fc *Cluster) listStored(tracker testing.ObjectTracker, gvr schema.GroupVersionResource) ([]*unstructured.Unstructured, error) {
+	gvk, ok := fc.kindFor(gvr)
+	if !ok {
+		return nil, fmt.Errorf("resource %s is not registered", gvr)
+	}
+
+	list, err := tracker.List(gvr, gvk, "")
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := make([]*unstructured.Unstructured, 0, len(items))
+
+	for _, item := range items {
+		obj, err := toUnstructured(item)
+		if err != nil {
+			return nil, err
+		}
+
+		// typed objects are stored without apiVersion and kind
+		obj.SetGroupVersionKind(gvk)
+		objs = append(objs, obj)
+	}
+
+	return objs, nil
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.DeepCopy(), nil
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+func objectKey(obj *unstructured.Unstructured) types.NamespacedName {
+	return types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}
+}
@@ -0,0 +1,545 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/testing"
+)
+
+// store wraps the object tracker and adds apiserver behavior missing in the fake clients
+type store struct {
+	testing.ObjectTracker
+	fc *Cluster
+	// scheme converts objects for the tracker of the typed clientset, it is nil for the dynamic client
+	scheme *runtime.Scheme
+
+	*storeState
+}
+
+// storeState is shared by stores of the dynamic and typed clients, so resource versions are cluster-wide
+type storeState struct {
+	// mu makes read-check-write sequences of mutating calls atomic
+	mu              sync.Mutex
+	resourceVersion uint64
+}
+
+var _ testing.ObjectTracker = &store{}
+
+// Create sets server-populated metadata: name from generateName, uid, creationTimestamp,
+// generation and resourceVersion.
+func (s *store) Create(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.CreateOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.create(gvr, obj, ns, opts...)
+}
+
+func (s *store) create(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.CreateOptions) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	if accessor.GetResourceVersion() != "" {
+		return apierrors.NewBadRequest("resourceVersion should not be set on objects to be created")
+	}
+
+	if accessor.GetName() == "" {
+		if accessor.GetGenerateName() == "" {
+			return apierrors.NewInvalid(s.groupKind(gvr), "", field.ErrorList{
+				field.Required(field.NewPath("metadata", "name"), "name or generateName is required"),
+			})
+		}
+
+		// the same as names.SimpleNameGenerator of apiserver
+		accessor.SetName(accessor.GetGenerateName() + utilrand.String(5))
+	}
+
+	accessor.SetUID(uuid.NewUUID())
+	accessor.SetCreationTimestamp(metav1.Now())
+	accessor.SetGeneration(1)
+
+	// as in apiserver, hooks get the object with the generated name
+	err = s.fc.admit(admissionv1.Create, gvr, "", ns, obj, nil)
+	if err != nil {
+		return err
+	}
+
+	accessor.SetResourceVersion(s.nextResourceVersion())
+
+	return s.ObjectTracker.Create(gvr, obj, ns, opts...)
+}
+
+// Update rejects stale updates with Conflict, keeps immutable metadata
+// and bumps generation if anything except metadata and status is changed.
+// Status is kept for resources with the status subresource.
+func (s *store) Update(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.UpdateOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(gvr, obj, ns, opts...)
+}
+
+// Patch handles a patched object the same way as Update, a stale resourceVersion in the patch causes Conflict
+func (s *store) Patch(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.PatchOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(gvr, obj, ns)
+}
+
+func (s *store) update(gvr schema.GroupVersionResource, obj runtime.Object, ns string, opts ...metav1.UpdateOptions) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	old, err := s.getStored(gvr, ns, accessor.GetName(), accessor.GetResourceVersion())
+	if err != nil {
+		return err
+	}
+
+	accessor.SetUID(old.GetUID())
+	accessor.SetCreationTimestamp(old.GetCreationTimestamp())
+	accessor.SetGeneration(old.GetGeneration())
+
+	// status is changed only with the status subresource if the resource has one
+	if s.fc.subresourcesFor(gvr).status {
+		u, err := s.asUnstructured(gvr, obj)
+		if err != nil {
+			return err
+		}
+
+		if status, found := old.Object["status"]; found {
+			u.Object["status"] = status
+		} else {
+			delete(u.Object, "status")
+		}
+
+		err = setObject(obj, u)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.fc.admit(admissionv1.Update, gvr, "", ns, obj, old)
+	if err != nil {
+		return err
+	}
+
+	changed, err := specChanged(old, obj)
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		accessor.SetGeneration(old.GetGeneration() + 1)
+	}
+
+	accessor.SetResourceVersion(s.nextResourceVersion())
+
+	return s.ObjectTracker.Update(gvr, obj, ns, opts...)
+}
+
+// Apply merges the applied configuration into the stored object or creates a new one.
+// Field ownership is not tracked, the configuration is applied as a JSON merge patch.
+func (s *store) Apply(gvr schema.GroupVersionResource, applyConfiguration runtime.Object, ns string, _ ...metav1.PatchOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	applied, err := toUnstructured(applyConfiguration)
+	if err != nil {
+		return err
+	}
+
+	old, err := s.ObjectTracker.Get(gvr, ns, applied.GetName())
+	if apierrors.IsNotFound(err) {
+		obj, err := s.toStored(gvr, applied)
+		if err != nil {
+			return err
+		}
+
+		return s.create(gvr, obj, ns)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	merged, err := toUnstructured(old)
+	if err != nil {
+		return err
+	}
+
+	merged.Object = mergePatch(merged.Object, applied.Object).(map[string]interface{})
+
+	obj, err := s.toStored(gvr, merged)
+	if err != nil {
+		return err
+	}
+
+	return s.update(gvr, obj, ns)
+}
+
+// Delete checks uid and resourceVersion preconditions
+func (s *store) Delete(gvr schema.GroupVersionResource, ns, name string, opts ...metav1.DeleteOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, err := s.getStored(gvr, ns, name, "")
+	if err != nil {
+		return err
+	}
+
+	var preconditions *metav1.Preconditions
+	if len(opts) > 0 {
+		preconditions = opts[0].Preconditions
+	}
+
+	if preconditions != nil && preconditions.UID != nil && *preconditions.UID != old.GetUID() {
+		return apierrors.NewConflict(gvr.GroupResource(), name,
+			fmt.Errorf("precondition failed: UID in precondition: %v, UID in object meta: %v", *preconditions.UID, old.GetUID()))
+	}
+
+	if preconditions != nil && preconditions.ResourceVersion != nil && *preconditions.ResourceVersion != old.GetResourceVersion() {
+		return apierrors.NewConflict(gvr.GroupResource(), name,
+			fmt.Errorf("precondition failed: ResourceVersion in precondition: %v, ResourceVersion in object meta: %v", *preconditions.ResourceVersion, old.GetResourceVersion()))
+	}
+
+	err = s.fc.admit(admissionv1.Delete, gvr, "", ns, nil, old)
+	if err != nil {
+		return err
+	}
+
+	return s.ObjectTracker.Delete(gvr, ns, name, opts...)
+}
+
+// getStored returns a copy of the stored object, a non-empty resourceVersion
+// that differs from the stored one causes Conflict
+func (s *store) getStored(gvr schema.GroupVersionResource, ns, name, resourceVersion string) (*unstructured.Unstructured, error) {
+	obj, err := s.ObjectTracker.Get(gvr, ns, name)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := toUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	if resourceVersion != "" && resourceVersion != old.GetResourceVersion() {
+		return nil, apierrors.NewConflict(gvr.GroupResource(), name, errors.New(conflictMessage))
+	}
+
+	return old, nil
+}
+
+// asUnstructured returns the object itself if it is unstructured. Typed objects are converted
+// with apiVersion and kind of the resource, changes are written back with setObject.
+func (s *store) asUnstructured(gvr schema.GroupVersionResource, obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// objects of the typed clientset have no apiVersion and kind
+	if gvk, ok := s.fc.kindFor(gvr); ok && u.GetKind() == "" {
+		u.SetGroupVersionKind(gvk)
+	}
+
+	return u, nil
+}
+
+// setObject writes changes of the unstructured copy back to the typed object
+func setObject(obj runtime.Object, u *unstructured.Unstructured) error {
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		return nil
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+}
+
+// toStored converts the object to the type kept by the tracker, the tracker of the typed clientset
+// returns stored objects to typed clients as is
+func (s *store) toStored(gvr schema.GroupVersionResource, u *unstructured.Unstructured) (runtime.Object, error) {
+	if s.scheme == nil {
+		return u, nil
+	}
+
+	gvk := u.GroupVersionKind()
+	if gvk.Empty() {
+		gvk, _ = s.fc.kindFor(gvr)
+	}
+
+	obj, err := s.scheme.New(gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, obj)
+}
+
+const conflictMessage = "the object has been modified; please apply your changes to the latest version and try again"
+
+func (s *store) nextResourceVersion() string {
+	s.resourceVersion++
+	return strconv.FormatUint(s.resourceVersion, 10)
+}
+
+func (s *store) currentResourceVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return strconv.FormatUint(s.resourceVersion, 10)
+}
+
+func (s *store) groupKind(gvr schema.GroupVersionResource) schema.GroupKind {
+	gvk, _ := s.fc.kindFor(gvr)
+	return gvk.GroupKind()
+}
+
+// specChanged is true if objects differ in anything except metadata and status
+func specChanged(old, obj runtime.Object) (bool, error) {
+	oldSpec, err := specJSON(old)
+	if err != nil {
+		return false, err
+	}
+
+	newSpec, err := specJSON(obj)
+	if err != nil {
+		return false, err
+	}
+
+	return !bytes.Equal(oldSpec, newSpec), nil
+}
+
+func specJSON(obj runtime.Object) ([]byte, error) {
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(u.Object, "metadata")
+	delete(u.Object, "status")
+	delete(u.Object, "apiVersion")
+	delete(u.Object, "kind")
+
+	return json.Marshal(u.Object)
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to the value
+func mergePatch(value, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	valueMap, ok := value.(map[string]interface{})
+	if !ok {
+		valueMap = make(map[string]interface{}, len(patchMap))
+	}
+
+	for k, v := range patchMap {
+		if v == nil {
+			delete(valueMap, k)
+			continue
+		}
+
+		valueMap[k] = mergePatch(valueMap[k], v)
+	}
+
+	return valueMap
+}
+
+// List returns objects filtered by label and field selectors from list options
+func (s *store) List(gvr schema.GroupVersionResource, gvk schema.GroupVersionKind, ns string, opts ...metav1.ListOptions) (runtime.Object, error) {
+	filter, err := s.fc.newObjectFilter(gvr, firstListOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.ObjectTracker.List(gvr, gvk, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	listAccessor, err := meta.ListAccessor(list)
+	if err != nil {
+		return nil, err
+	}
+
+	listAccessor.SetResourceVersion(s.currentResourceVersion())
+
+	if filter == nil {
+		return list, nil
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]runtime.Object, 0, len(items))
+
+	for _, item := range items {
+		if filter.matches(item) {
+			matched = append(matched, item)
+		}
+	}
+
+	if err := meta.SetList(list, matched); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Watch returns events for objects matching label and field selectors from list options.
+// As in apiserver, an object modified to match the selectors is sent as ADDED and
+// an object modified to not match anymore is sent as DELETED.
+func (s *store) Watch(gvr schema.GroupVersionResource, ns string, opts ...metav1.ListOptions) (watch.Interface, error) {
+	filter, err := s.fc.newObjectFilter(gvr, firstListOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := s.ObjectTracker.Watch(gvr, ns)
+	if err != nil || filter == nil {
+		return w, err
+	}
+
+	visible := make(map[types.NamespacedName]struct{})
+
+	if gvk, ok := s.fc.kindFor(gvr); ok {
+		list, err := s.ObjectTracker.List(gvr, gvk, ns)
+		if err != nil {
+			w.Stop()
+			return nil, err
+		}
+
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			w.Stop()
+			return nil, err
+		}
+
+		for _, item := range items {
+			if filter.matches(item) {
+				visible[runtimeObjectKey(item)] = struct{}{}
+			}
+		}
+	}
+
+	fw := &filteredWatch{
+		source:  w,
+		filter:  filter,
+		visible: visible,
+		result:  make(chan watch.Event),
+		done:    make(chan struct{}),
+	}
+	go fw.run()
+
+	return fw, nil
+}
+
+func firstListOptions(opts []metav1.ListOptions) metav1.ListOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+
+	return metav1.ListOptions{}
+}
+
+func runtimeObjectKey(obj runtime.Object) types.NamespacedName {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return types.NamespacedName{}
+	}
+
+	return types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+}
+
+// filteredWatch passes events of objects matching the filter
+type filteredWatch struct {
+	source  watch.Interface
+	filter  *objectFilter
+	visible map[types.NamespacedName]struct{}
+	result  chan watch.Event
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (w *filteredWatch) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+		w.source.Stop()
+	})
+}
+
+func (w *filteredWatch) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+func (w *filteredWatch) run() {
+	defer close(w.result)
+
+	for event := range w.source.ResultChan() {
+		event, ok := w.translate(event)
+		if !ok {
+			continue
+		}
+
+		select {
+		case w.result <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *filteredWatch) translate(event watch.Event) (watch.Event, bool) {
+	if event.Type != watch.Added && event.Type != watch.Modified && event.Type != watch.Deleted {
+		return event, true
+	}
+
+	key := runtimeObjectKey(event.Object)
+	_, wasVisible := w.visible[key]
+	matches := event.Type != watch.Deleted && w.filter.matches(event.Object)
+
+	switch {
+	case matches && wasVisible:
+		event.Type = watch.Modified
+	case matches:
+		event.Type = watch.Added
+		w.visible[key] = struct{}{}
+	case wasVisible:
+		event.Type = watch.Deleted
+		delete(w.visible, key)
+	default:
+		return event, false
+	}
+
+	return event, true
+}
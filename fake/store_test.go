@@ -0,0 +1,216 @@
+package fake
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+func TestObjectMetadata(t *testing.T) {
+	f := NewFakeCluster("")
+	deployments := f.Client.Dynamic().Resource(*f.MustFindGVR("apps/v1", "Deployment")).Namespace("default")
+
+	created, err := deployments.Create(context.TODO(), manifest.MustFromYAML(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  generateName: web-
+spec:
+  replicas: 1
+`).Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+	require.Regexp(t, "^web-[a-z0-9]{5}$", created.GetName())
+	require.NotEmpty(t, created.GetUID())
+	require.NotEmpty(t, created.GetCreationTimestamp())
+	require.Equal(t, int64(1), created.GetGeneration())
+	require.NotEmpty(t, created.GetResourceVersion())
+
+	t.Run("metadata change keeps generation", func(t *testing.T) {
+		obj := created.DeepCopy()
+		obj.SetLabels(map[string]string{"app": "web"})
+		obj.SetUID("")
+
+		updated, err := deployments.Update(context.TODO(), obj, v1.UpdateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), updated.GetGeneration())
+		require.Equal(t, created.GetUID(), updated.GetUID())
+		require.Greater(t, resourceVersion(t, updated), resourceVersion(t, created))
+	})
+
+	t.Run("spec change bumps generation", func(t *testing.T) {
+		patched, err := deployments.Patch(context.TODO(), created.GetName(), types.MergePatchType, []byte(`{"spec":{"replicas":2}}`), v1.PatchOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), patched.GetGeneration())
+	})
+
+	t.Run("create requires name", func(t *testing.T) {
+		_, err := deployments.Create(context.TODO(), manifest.New("apps/v1", "Deployment", "").Unstructured(), v1.CreateOptions{})
+		require.True(t, apierrors.IsInvalid(err))
+	})
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	f := NewFakeCluster("")
+	f.CreateSimpleNamespaced("default", "ConfigMap", "foo")
+
+	cms := f.Client.Dynamic().Resource(configMapsGVR).Namespace("default")
+
+	stale, err := cms.Get(context.TODO(), "foo", v1.GetOptions{})
+	require.NoError(t, err)
+
+	_, err = cms.Patch(context.TODO(), "foo", types.MergePatchType, []byte(`{"data":{"a":"1"}}`), v1.PatchOptions{})
+	require.NoError(t, err)
+
+	t.Run("stale update", func(t *testing.T) {
+		_, err := cms.Update(context.TODO(), stale, v1.UpdateOptions{})
+		require.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("stale patch", func(t *testing.T) {
+		patch := `{"metadata":{"resourceVersion":"` + stale.GetResourceVersion() + `"},"data":{"a":"2"}}`
+		_, err := cms.Patch(context.TODO(), "foo", types.MergePatchType, []byte(patch), v1.PatchOptions{})
+		require.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("retry on conflict", func(t *testing.T) {
+		attempts := 0
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			attempts++
+
+			obj := stale
+			if attempts > 1 {
+				var err error
+
+				obj, err = cms.Get(context.TODO(), "foo", v1.GetOptions{})
+				require.NoError(t, err)
+			}
+
+			obj = obj.DeepCopy()
+			_ = unstructured.SetNestedField(obj.Object, "3", "data", "a")
+			_, err := cms.Update(context.TODO(), obj, v1.UpdateOptions{})
+
+			return err
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("delete precondition", func(t *testing.T) {
+		rv := stale.GetResourceVersion()
+		err := cms.Delete(context.TODO(), "foo", v1.DeleteOptions{Preconditions: &v1.Preconditions{ResourceVersion: &rv}})
+		require.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("create with resourceVersion", func(t *testing.T) {
+		_, err := cms.Create(context.TODO(), stale, v1.CreateOptions{})
+		require.True(t, apierrors.IsBadRequest(err))
+	})
+}
+
+func TestApply(t *testing.T) {
+	f := NewFakeCluster("")
+	cms := f.Client.Dynamic().Resource(configMapsGVR).Namespace("default")
+
+	cm := manifest.MustFromYAML("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\ndata:\n  a: '1'\n")
+
+	applied, err := cms.Apply(context.TODO(), "foo", cm.Unstructured(), v1.ApplyOptions{FieldManager: "test"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), applied.GetGeneration())
+
+	cm = manifest.MustFromYAML("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\ndata:\n  b: '2'\n")
+
+	applied, err = cms.Apply(context.TODO(), "foo", cm.Unstructured(), v1.ApplyOptions{FieldManager: "test"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, applied.Object["data"])
+	require.Equal(t, int64(2), applied.GetGeneration())
+}
+
+func TestTypedClient(t *testing.T) {
+	f := NewFakeCluster("")
+	cms := f.Client.CoreV1().ConfigMaps("default")
+
+	created, err := cms.Create(context.TODO(), &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{GenerateName: "cm-"},
+		Data:       map[string]string{"a": "1"},
+	}, v1.CreateOptions{})
+	require.NoError(t, err)
+	require.Regexp(t, "^cm-[a-z0-9]{5}$", created.Name)
+	require.NotEmpty(t, created.UID)
+	require.NotEmpty(t, created.CreationTimestamp)
+	require.Equal(t, int64(1), created.Generation)
+	require.NotEmpty(t, created.ResourceVersion)
+
+	t.Run("update bumps generation", func(t *testing.T) {
+		obj := created.DeepCopy()
+		obj.Data["a"] = "2"
+
+		updated, err := cms.Update(context.TODO(), obj, v1.UpdateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), updated.Generation)
+		require.Equal(t, created.UID, updated.UID)
+	})
+
+	t.Run("stale update", func(t *testing.T) {
+		_, err := cms.Update(context.TODO(), created, v1.UpdateOptions{})
+		require.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		applied, err := cms.Apply(context.TODO(), corev1ac.ConfigMap(created.Name, "default").WithData(map[string]string{"b": "3"}),
+			v1.ApplyOptions{FieldManager: "test"})
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"a": "2", "b": "3"}, applied.Data)
+		require.Equal(t, int64(3), applied.Generation)
+	})
+
+	t.Run("status and scale", func(t *testing.T) {
+		deployments := f.Client.AppsV1().Deployments("default")
+		replicas := int32(1)
+
+		deploy, err := deployments.Create(context.TODO(), &appsv1.Deployment{
+			ObjectMeta: v1.ObjectMeta{Name: "web"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		}, v1.CreateOptions{})
+		require.NoError(t, err)
+
+		deploy.Status.Replicas = 1
+		deploy, err = deployments.UpdateStatus(context.TODO(), deploy, v1.UpdateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int32(1), deploy.Status.Replicas)
+
+		deploy.Status.Replicas = 5
+		deploy, err = deployments.Update(context.TODO(), deploy, v1.UpdateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int32(1), deploy.Status.Replicas)
+
+		scale, err := deployments.GetScale(context.TODO(), "web", v1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int32(1), scale.Spec.Replicas)
+
+		scale.Spec.Replicas = 3
+		scale, err = deployments.UpdateScale(context.TODO(), "web", scale, v1.UpdateOptions{})
+		require.NoError(t, err)
+		require.Equal(t, int32(3), scale.Spec.Replicas)
+	})
+}
+
+func resourceVersion(t *testing.T, obj *unstructured.Unstructured) int {
+	t.Helper()
+
+	rv, err := strconv.Atoi(obj.GetResourceVersion())
+	require.NoError(t, err)
+
+	return rv
+}
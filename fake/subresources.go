@@ -0,0 +1,393 @@
+package fake
+
+import (
+	"fmt"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/testing"
+)
+
+// subresources describes status and scale subresources of a resource
+type subresources struct {
+	status bool
+	scale  *scaleSubresource
+}
+
+// scaleSubresource describes how the scale subresource maps to object fields.
+// Paths are dot separated with an optional leading dot, as in CRD definitions.
+type scaleSubresource struct {
+	specReplicasPath   string
+	statusReplicasPath string
+	labelSelectorPath  string
+	// selectorFromSpec makes status.selector of Scale from spec.selector of the object, as built-in workloads do
+	selectorFromSpec bool
+}
+
+var workloadScale = &scaleSubresource{
+	specReplicasPath:   ".spec.replicas",
+	statusReplicasPath: ".status.replicas",
+	selectorFromSpec:   true,
+}
+
+// builtinSubresources lists status and scale subresources of built-in resources,
+// resource tables of cluster versions don't contain subresources
+var builtinSubresources = map[schema.GroupResource]subresources{
+	{Resource: "namespaces"}:                                               {status: true},
+	{Resource: "nodes"}:                                                    {status: true},
+	{Resource: "persistentvolumeclaims"}:                                   {status: true},
+	{Resource: "persistentvolumes"}:                                        {status: true},
+	{Resource: "pods"}:                                                     {status: true},
+	{Resource: "replicationcontrollers"}:                                   {status: true, scale: workloadScale},
+	{Resource: "resourcequotas"}:                                           {status: true},
+	{Resource: "services"}:                                                 {status: true},
+	{Group: "apps", Resource: "daemonsets"}:                                {status: true},
+	{Group: "apps", Resource: "deployments"}:                               {status: true, scale: workloadScale},
+	{Group: "apps", Resource: "replicasets"}:                               {status: true, scale: workloadScale},
+	{Group: "apps", Resource: "statefulsets"}:                              {status: true, scale: workloadScale},
+	{Group: "autoscaling", Resource: "horizontalpodautoscalers"}:           {status: true},
+	{Group: "batch", Resource: "cronjobs"}:                                 {status: true},
+	{Group: "batch", Resource: "jobs"}:                                     {status: true},
+	{Group: "certificates.k8s.io", Resource: "certificatesigningrequests"}: {status: true},
+	{Group: "networking.k8s.io", Resource: "ingresses"}:                    {status: true},
+	{Group: "policy", Resource: "poddisruptionbudgets"}:                    {status: true},
+	{Group: "storage.k8s.io", Resource: "volumeattachments"}:               {status: true},
+	{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"}: {status: true},
+	{Group: "apiregistration.k8s.io", Resource: "apiservices"}:             {status: true},
+}
+
+func crdSubresources(subs *apiextensionsv1.CustomResourceSubresources) subresources {
+	var res subresources
+
+	if subs == nil {
+		return res
+	}
+
+	res.status = subs.Status != nil
+
+	if scale := subs.Scale; scale != nil {
+		res.scale = &scaleSubresource{
+			specReplicasPath:   scale.SpecReplicasPath,
+			statusReplicasPath: scale.StatusReplicasPath,
+		}
+
+		if scale.LabelSelectorPath != nil {
+			res.scale.labelSelectorPath = *scale.LabelSelectorPath
+		}
+	}
+
+	return res
+}
+
+func (fc *Cluster) subresourcesFor(gvr schema.GroupVersionResource) subresources {
+	if res, ok := fc.crdSubresources[gvr]; ok {
+		return res
+	}
+
+	return builtinSubresources[gvr.GroupResource()]
+}
+
+// subresourceTracker returns the store view handling calls of the subresource
+func (fc *Cluster) subresourceTracker(s *store, gvr schema.GroupVersionResource, subresource string) (testing.ObjectTracker, error) {
+	subs := fc.subresourcesFor(gvr)
+
+	switch subresource {
+	case "status":
+		if !subs.status {
+			return nil, subresourceNotFound(gvr, subresource)
+		}
+
+		return statusView{store: s}, nil
+	case "scale":
+		if subs.scale == nil {
+			return nil, subresourceNotFound(gvr, subresource)
+		}
+
+		return scaleView{store: s, scale: subs.scale}, nil
+	default:
+		return s, nil
+	}
+}
+
+func subresourceNotFound(gvr schema.GroupVersionResource, subresource string) error {
+	return apierrors.NewNotFound(schema.GroupResource{
+		Group:    gvr.Group,
+		Resource: gvr.Resource + "/" + subresource,
+	}, "")
+}
+
+// statusView is the store behind the status subresource, only status of objects is updated
+type statusView struct {
+	*store
+}
+
+func (v statusView) Update(gvr schema.GroupVersionResource, obj runtime.Object, ns string, _ ...metav1.UpdateOptions) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.updateStatus(gvr, obj, ns)
+}
+
+func (v statusView) Patch(gvr schema.GroupVersionResource, obj runtime.Object, ns string, _ ...metav1.PatchOptions) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.updateStatus(gvr, obj, ns)
+}
+
+func (v statusView) updateStatus(gvr schema.GroupVersionResource, obj runtime.Object, ns string) error {
+	u, err := v.asUnstructured(gvr, obj)
+	if err != nil {
+		return err
+	}
+
+	old, err := v.getStored(gvr, ns, u.GetName(), u.GetResourceVersion())
+	if err != nil {
+		return err
+	}
+
+	updated := old.DeepCopy()
+
+	status, hasStatus := u.Object["status"]
+	if hasStatus {
+		updated.Object["status"] = status
+	} else {
+		delete(updated.Object, "status")
+	}
+
+	err = v.fc.admit(admissionv1.Update, gvr, "status", ns, updated, old)
+	if err != nil {
+		return err
+	}
+
+	updated.SetResourceVersion(v.nextResourceVersion())
+
+	stored, err := v.toStored(gvr, updated)
+	if err != nil {
+		return err
+	}
+
+	err = v.ObjectTracker.Update(gvr, stored, ns)
+	if err != nil {
+		return err
+	}
+
+	u.Object = updated.Object
+
+	return setObject(obj, u)
+}
+
+// scaleView is the store behind the scale subresource, it reads and writes autoscaling/v1 Scale objects
+type scaleView struct {
+	*store
+	scale *scaleSubresource
+}
+
+func (v scaleView) Get(gvr schema.GroupVersionResource, ns, name string, _ ...metav1.GetOptions) (runtime.Object, error) {
+	obj, err := v.ObjectTracker.Get(gvr, ns, name)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := toUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, err := v.scale.toScale(u)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.toStored(gvr, scale)
+}
+
+func (v scaleView) Update(gvr schema.GroupVersionResource, obj runtime.Object, ns string, _ ...metav1.UpdateOptions) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.updateScale(gvr, obj, ns)
+}
+
+func (v scaleView) Patch(gvr schema.GroupVersionResource, obj runtime.Object, ns string, _ ...metav1.PatchOptions) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.updateScale(gvr, obj, ns)
+}
+
+func (v scaleView) updateScale(gvr schema.GroupVersionResource, obj runtime.Object, ns string) error {
+	scale, err := v.asUnstructured(gvr, obj)
+	if err != nil {
+		return err
+	}
+
+	// typed Scale objects have no apiVersion and kind
+	scale.SetAPIVersion("autoscaling/v1")
+	scale.SetKind("Scale")
+
+	old, err := v.getStored(gvr, ns, scale.GetName(), scale.GetResourceVersion())
+	if err != nil {
+		return err
+	}
+
+	oldScale, err := v.scale.toScale(old)
+	if err != nil {
+		return err
+	}
+
+	err = v.fc.admit(admissionv1.Update, gvr, "scale", ns, scale, oldScale)
+	if err != nil {
+		return err
+	}
+
+	replicas, err := int64Field(scale.Object, "spec", "replicas")
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	updated := old.DeepCopy()
+
+	err = unstructured.SetNestedField(updated.Object, replicas, fieldPath(v.scale.specReplicasPath)...)
+	if err != nil {
+		return err
+	}
+
+	changed, err := specChanged(old, updated)
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		updated.SetGeneration(old.GetGeneration() + 1)
+	}
+
+	updated.SetResourceVersion(v.nextResourceVersion())
+
+	stored, err := v.toStored(gvr, updated)
+	if err != nil {
+		return err
+	}
+
+	err = v.ObjectTracker.Update(gvr, stored, ns)
+	if err != nil {
+		return err
+	}
+
+	newScale, err := v.scale.toScale(updated)
+	if err != nil {
+		return err
+	}
+
+	scale.Object = newScale.Object
+
+	return setObject(obj, scale)
+}
+
+// toScale makes an autoscaling/v1 Scale object for the object
+func (s *scaleSubresource) toScale(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	specReplicas, err := int64Field(obj.Object, fieldPath(s.specReplicasPath)...)
+	if err != nil {
+		return nil, err
+	}
+
+	statusReplicas, err := int64Field(obj.Object, fieldPath(s.statusReplicasPath)...)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := s.selector(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "autoscaling/v1",
+		"kind":       "Scale",
+		"spec": map[string]interface{}{
+			"replicas": specReplicas,
+		},
+		"status": map[string]interface{}{
+			"replicas": statusReplicas,
+		},
+	}}
+	scale.SetName(obj.GetName())
+	scale.SetNamespace(obj.GetNamespace())
+	scale.SetUID(obj.GetUID())
+	scale.SetResourceVersion(obj.GetResourceVersion())
+	scale.SetCreationTimestamp(obj.GetCreationTimestamp())
+
+	if selector != "" {
+		_ = unstructured.SetNestedField(scale.Object, selector, "status", "selector")
+	}
+
+	return scale, nil
+}
+
+func (s *scaleSubresource) selector(obj *unstructured.Unstructured) (string, error) {
+	if !s.selectorFromSpec {
+		if s.labelSelectorPath == "" {
+			return "", nil
+		}
+
+		selector, _, _ := unstructured.NestedString(obj.Object, fieldPath(s.labelSelectorPath)...)
+
+		return selector, nil
+	}
+
+	spec, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if !found {
+		return "", nil
+	}
+
+	_, hasLabels := spec["matchLabels"]
+	_, hasExpressions := spec["matchExpressions"]
+
+	if !hasLabels && !hasExpressions {
+		// replication controllers use a plain map as a selector
+		set, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+		return labels.SelectorFromSet(set).String(), nil
+	}
+
+	labelSelector := &metav1.LabelSelector{}
+
+	err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, labelSelector)
+	if err != nil {
+		return "", err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return "", err
+	}
+
+	return selector.String(), nil
+}
+
+func fieldPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "."), ".")
+}
+
+func int64Field(obj map[string]interface{}, path ...string) (int64, error) {
+	val, _, _ := unstructured.NestedFieldNoCopy(obj, path...)
+
+	switch v := val.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case float64:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("%s must be an integer, got %T", strings.Join(path, "."), val)
+	}
+}
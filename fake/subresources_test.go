@@ -0,0 +1,183 @@
+package fake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/flant/kube-client/manifest"
+)
+
+func TestStatusSubresource(t *testing.T) {
+	f := NewFakeCluster("")
+	deployments := f.Client.Dynamic().Resource(*f.MustFindGVR("apps/v1", "Deployment")).Namespace("default")
+
+	created, err := deployments.Create(context.TODO(), manifest.MustFromYAML(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+status:
+  replicas: 1
+`).Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+
+	t.Run("update status touches only status", func(t *testing.T) {
+		obj := created.DeepCopy()
+		_ = unstructured.SetNestedField(obj.Object, int64(5), "spec", "replicas")
+		_ = unstructured.SetNestedField(obj.Object, int64(3), "status", "readyReplicas")
+
+		updated, err := deployments.UpdateStatus(context.TODO(), obj, v1.UpdateOptions{})
+		require.NoError(t, err)
+
+		replicas, _ := int64Field(updated.Object, "spec", "replicas")
+		ready, _ := int64Field(updated.Object, "status", "readyReplicas")
+		require.Equal(t, int64(1), replicas)
+		require.Equal(t, int64(3), ready)
+		require.Equal(t, int64(1), updated.GetGeneration())
+		require.Greater(t, resourceVersion(t, updated), resourceVersion(t, created))
+	})
+
+	t.Run("update ignores status", func(t *testing.T) {
+		obj, err := deployments.Get(context.TODO(), "web", v1.GetOptions{})
+		require.NoError(t, err)
+
+		unstructured.RemoveNestedField(obj.Object, "status")
+
+		updated, err := deployments.Update(context.TODO(), obj, v1.UpdateOptions{})
+		require.NoError(t, err)
+
+		ready, _ := int64Field(updated.Object, "status", "readyReplicas")
+		require.Equal(t, int64(3), ready)
+	})
+
+	t.Run("patch status", func(t *testing.T) {
+		patched, err := deployments.Patch(context.TODO(), "web", types.MergePatchType,
+			[]byte(`{"metadata":{"labels":{"a":"b"}},"status":{"readyReplicas":4}}`), v1.PatchOptions{}, "status")
+		require.NoError(t, err)
+
+		ready, _ := int64Field(patched.Object, "status", "readyReplicas")
+		require.Equal(t, int64(4), ready)
+		require.Empty(t, patched.GetLabels())
+	})
+
+	t.Run("stale status update", func(t *testing.T) {
+		_, err := deployments.UpdateStatus(context.TODO(), created, v1.UpdateOptions{})
+		require.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("resource without status", func(t *testing.T) {
+		f.CreateSimpleNamespaced("default", "ConfigMap", "foo")
+		cms := f.Client.Dynamic().Resource(configMapsGVR).Namespace("default")
+
+		cm, err := cms.Get(context.TODO(), "foo", v1.GetOptions{})
+		require.NoError(t, err)
+
+		_, err = cms.UpdateStatus(context.TODO(), cm, v1.UpdateOptions{})
+		require.True(t, apierrors.IsNotFound(err))
+	})
+}
+
+func TestScaleSubresource(t *testing.T) {
+	f := NewFakeCluster("")
+	deployments := f.Client.Dynamic().Resource(*f.MustFindGVR("apps/v1", "Deployment")).Namespace("default")
+
+	_, err := deployments.Create(context.TODO(), manifest.MustFromYAML(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+status:
+  replicas: 1
+`).Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+
+	scale, err := deployments.Get(context.TODO(), "web", v1.GetOptions{}, "scale")
+	require.NoError(t, err)
+	require.Equal(t, "Scale", scale.GetKind())
+	require.Equal(t, "autoscaling/v1", scale.GetAPIVersion())
+	require.Equal(t, map[string]interface{}{"replicas": int64(1), "selector": "app=web"}, scale.Object["status"])
+
+	_ = unstructured.SetNestedField(scale.Object, int64(3), "spec", "replicas")
+
+	scale, err = deployments.Update(context.TODO(), scale, v1.UpdateOptions{}, "scale")
+	require.NoError(t, err)
+
+	replicas, _ := int64Field(scale.Object, "spec", "replicas")
+	require.Equal(t, int64(3), replicas)
+
+	scale, err = deployments.Patch(context.TODO(), "web", types.MergePatchType, []byte(`{"spec":{"replicas":5}}`), v1.PatchOptions{}, "scale")
+	require.NoError(t, err)
+
+	replicas, _ = int64Field(scale.Object, "spec", "replicas")
+	require.Equal(t, int64(5), replicas)
+
+	obj, err := deployments.Get(context.TODO(), "web", v1.GetOptions{})
+	require.NoError(t, err)
+
+	replicas, _ = int64Field(obj.Object, "spec", "replicas")
+	require.Equal(t, int64(5), replicas)
+	require.Equal(t, int64(3), obj.GetGeneration())
+}
+
+func TestCRDSubresources(t *testing.T) {
+	f := NewFakeCluster("")
+	labelSelectorPath := ".status.selector"
+	f.RegisterCRDDefinition(&apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Pool", Plural: "pools"},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:   "v1",
+				Served: true,
+				Subresources: &apiextensionsv1.CustomResourceSubresources{
+					Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+					Scale: &apiextensionsv1.CustomResourceSubresourceScale{
+						SpecReplicasPath:   ".spec.size",
+						StatusReplicasPath: ".status.size",
+						LabelSelectorPath:  &labelSelectorPath,
+					},
+				},
+			}},
+		},
+	})
+
+	pools := f.Client.Dynamic().Resource(*f.MustFindGVR("example.com/v1", "Pool")).Namespace("default")
+
+	_, err := pools.Create(context.TODO(), manifest.MustFromYAML(`
+apiVersion: example.com/v1
+kind: Pool
+metadata:
+  name: main
+spec:
+  size: 2
+status:
+  size: 1
+  selector: pool=main
+`).Unstructured(), v1.CreateOptions{})
+	require.NoError(t, err)
+
+	scale, err := pools.Patch(context.TODO(), "main", types.MergePatchType, []byte(`{"spec":{"replicas":4}}`), v1.PatchOptions{}, "scale")
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"replicas": int64(1), "selector": "pool=main"}, scale.Object["status"])
+
+	obj, err := pools.Get(context.TODO(), "main", v1.GetOptions{})
+	require.NoError(t, err)
+
+	size, _ := int64Field(obj.Object, "spec", "size")
+	require.Equal(t, int64(4), size)
+}
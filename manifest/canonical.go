@@ -0,0 +1,158 @@
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+)
+
+// serverFields are set by the API server and are not a part of the manifest content
+var serverFields = [][]string{
+	{"status"},
+	{"metadata", "uid"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "deletionTimestamp"},
+	{"metadata", "deletionGracePeriodSeconds"},
+	{"metadata", "managedFields"},
+	{"metadata", "selfLink"},
+}
+
+// Canonical returns a copy of the manifest without server-side fields, see serverFields,
+// and with integral numbers converted to int64, so 1, 1.0 and int(1) are the same value
+func (m Manifest) Canonical() Manifest {
+	res := Manifest(normalizeNumbers(map[string]interface{}(m.DeepCopy())).(map[string]interface{}))
+
+	for _, path := range serverFields {
+		removeField(res, path)
+	}
+
+	return res
+}
+
+// CanonicalJSON returns the canonical manifest as compact JSON with sorted keys
+func (m Manifest) CanonicalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	err := enc.Encode(map[string]interface{}(m.Canonical()))
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// CanonicalYAML returns the canonical manifest as YAML with sorted keys
+func (m Manifest) CanonicalYAML() ([]byte, error) {
+	return yaml.Marshal(map[string]interface{}(m.Canonical()))
+}
+
+// Hash returns the hex encoded SHA-256 of the canonical JSON
+func (m Manifest) Hash() (string, error) {
+	data, err := m.CanonicalJSON()
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Hash returns the hex encoded SHA-256 of the list that doesn't depend on the order of manifests.
+// Hashes of manifests are sorted and hashed together, so duplicates change the result.
+func (l ManifestList) Hash() (string, error) {
+	hashes := make([]string, 0, len(l))
+
+	for _, m := range l {
+		h, err := m.Hash()
+		if err != nil {
+			return "", err
+		}
+
+		hashes = append(hashes, h)
+	}
+
+	sort.Strings(hashes)
+
+	h := sha256.New()
+	for _, item := range hashes {
+		h.Write([]byte(item + "\n"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// normalizeNumbers converts numbers to int64 if they are integral and to float64 otherwise
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalizeNumbers(item)
+		}
+
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float32:
+		return normalizeFloat(float64(v))
+	case float64:
+		return normalizeFloat(v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		if f, err := v.Float64(); err == nil {
+			return normalizeFloat(f)
+		}
+
+		return v
+	default:
+		return v
+	}
+}
+
+func normalizeFloat(f float64) interface{} {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+
+	return f
+}
+
+// removeField removes the field and the parent maps that become empty
+func removeField(obj map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+
+	child, ok := obj[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	removeField(child, path[1:])
+
+	if len(child) == 0 {
+		delete(obj, path[0])
+	}
+}
@@ -0,0 +1,57 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_CanonicalJSON(t *testing.T) {
+	g := NewWithT(t)
+
+	live := MustFromYAML(`
+kind: Deployment
+apiVersion: apps/v1
+metadata:
+  name: web
+  uid: 1b4e
+  resourceVersion: "42"
+  managedFields: []
+  annotations:
+    url: "http://a/?b=<c>&d"
+spec:
+  replicas: 2.0
+  ratio: 0.5
+status:
+  readyReplicas: 2
+`)
+	rendered := New("apps/v1", "Deployment", "web")
+	rendered.SetAnnotation("url", "http://a/?b=<c>&d")
+	g.Expect(rendered.Set("spec.ratio", 0.5)).Should(Succeed())
+	g.Expect(rendered.Set("spec.replicas", 2)).Should(Succeed())
+
+	data, err := live.CanonicalJSON()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(data)).Should(Equal(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"annotations":{"url":"http://a/?b=<c>&d"},"name":"web"},"spec":{"ratio":0.5,"replicas":2}}`))
+
+	data, err = live.CanonicalYAML()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(data)).Should(HavePrefix("apiVersion: apps/v1\nkind: Deployment\n"))
+
+	liveHash, err := live.Hash()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rendered.Hash()).Should(Equal(liveHash))
+
+	g.Expect(live).Should(HaveKey("status"))
+}
+
+func Test_ManifestList_Hash(t *testing.T) {
+	g := NewWithT(t)
+
+	a, b := New("v1", "ConfigMap", "a"), New("v1", "ConfigMap", "b")
+
+	h1, err := ManifestList{a, b}.Hash()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(ManifestList{b, a}.Hash()).Should(Equal(h1))
+	g.Expect(ManifestList{a, b, b}.Hash()).ShouldNot(Equal(h1))
+}
@@ -0,0 +1,198 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// serverAnnotations are set by controllers and kubectl on all kinds
+var serverAnnotations = []string{
+	lastAppliedAnnotation,
+	"deployment.kubernetes.io/revision",
+}
+
+// cleanRules remove fields allocated or defaulted by the cluster by "Kind.group"
+var cleanRules = map[string]func(m Manifest){
+	"Service": func(m Manifest) {
+		spec, ok := nestedMap(m, "spec")
+		if !ok {
+			return
+		}
+
+		// "None" is set by the user for headless services
+		if spec["clusterIP"] != "None" {
+			delete(spec, "clusterIP")
+			delete(spec, "clusterIPs")
+		}
+
+		ports, healthCheck := userNodePorts(m)
+		if !healthCheck {
+			delete(spec, "healthCheckNodePort")
+		}
+
+		for _, port := range nestedMaps(spec, "ports") {
+			if !ports[servicePortKey(port)] {
+				delete(port, "nodePort")
+			}
+		}
+	},
+	"PersistentVolumeClaim": func(m Manifest) {
+		removeField(m, []string{"spec", "volumeName"})
+		removeAnnotations(m,
+			"pv.kubernetes.io/bind-completed",
+			"pv.kubernetes.io/bound-by-controller",
+			"volume.beta.kubernetes.io/storage-provisioner",
+			"volume.kubernetes.io/storage-provisioner",
+			"volume.kubernetes.io/selected-node",
+		)
+	},
+	"Job.batch": func(m Manifest) {
+		spec, ok := nestedMap(m, "spec")
+		// the generated selector has the uid of the Job, it is generated again on creation
+		if !ok || spec["manualSelector"] == true {
+			return
+		}
+
+		removeField(m, []string{"spec", "selector"})
+
+		for _, label := range []string{"controller-uid", "batch.kubernetes.io/controller-uid"} {
+			removeField(m, []string{"spec", "template", "metadata", "labels", label})
+		}
+	},
+	"Pod": func(m Manifest) {
+		removeField(m, []string{"spec", "nodeName"})
+	},
+	"Namespace": func(m Manifest) {
+		removeField(m, []string{"spec", "finalizers"})
+	},
+}
+
+// Cleaner converts live objects into manifests that can be applied to another cluster or namespace:
+// server-side fields, see Canonical, owner references and kubectl and controller annotations are removed
+// along with fields allocated by the cluster, e.g. Service clusterIP and PersistentVolumeClaim volumeName.
+// Node ports are kept only if they are set by users according to managed fields or the last-applied
+// configuration. Fields defaulted by the API server, e.g. Deployment strategy or container
+// terminationMessagePath, are kept as they can't be told apart from values set by users.
+type Cleaner struct {
+	// Paths are extra fields removed from all objects, e.g. "metadata.labels.team"
+	Paths []string
+	// KindPaths are extra fields removed from objects of a kind by "Kind.group", e.g. {"Deployment.apps": {"spec.replicas"}}
+	KindPaths map[string][]string
+	// Annotations are extra annotation keys removed from all objects
+	Annotations []string
+}
+
+// Clean returns a cleaned copy of the live object, missing extra paths are ignored
+func (c Cleaner) Clean(obj *unstructured.Unstructured) (Manifest, error) {
+	res := Manifest(obj.DeepCopy().Object)
+
+	// rules run first as they can look at managed fields and the last-applied configuration
+	gk := res.ObjectKey().GroupKind().String()
+	if rule, ok := cleanRules[gk]; ok {
+		rule(res)
+	}
+
+	for _, path := range serverFields {
+		removeField(res, path)
+	}
+
+	removeField(res, []string{"metadata", "ownerReferences"})
+	removeAnnotations(res, serverAnnotations...)
+	removeAnnotations(res, c.Annotations...)
+
+	for _, path := range append(append([]string(nil), c.Paths...), c.KindPaths[gk]...) {
+		if _, err := res.Remove(path); err != nil {
+			return nil, fmt.Errorf("%s: %w", res.ObjectKey(), err)
+		}
+	}
+
+	return res, nil
+}
+
+// CleanList returns cleaned copies of the live objects, see Clean
+func (c Cleaner) CleanList(objs []unstructured.Unstructured) (ManifestList, error) {
+	res := make(ManifestList, 0, len(objs))
+
+	for i := range objs {
+		m, err := c.Clean(&objs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, m)
+	}
+
+	return res, nil
+}
+
+// Clean returns a cleaned copy of the live object with the default rules, see Cleaner
+func Clean(obj *unstructured.Unstructured) (Manifest, error) {
+	return Cleaner{}.Clean(obj)
+}
+
+// removeAnnotations removes the annotations and the annotations map if it is empty
+func removeAnnotations(m Manifest, keys ...string) {
+	for _, key := range keys {
+		removeField(m, []string{"metadata", "annotations", key})
+	}
+}
+
+// userNodePorts returns Service ports with node ports set by users by servicePortKey and whether
+// healthCheckNodePort is set by users. Allocated node ports are neither in managed fields of any manager
+// nor in the last-applied configuration.
+func userNodePorts(m Manifest) (map[string]bool, bool) {
+	ports := make(map[string]bool)
+	healthCheck := false
+
+	for _, entry := range nestedMaps(m, "metadata", "managedFields") {
+		spec, ok := nestedMap(entry, "fieldsV1", "f:spec")
+		if !ok {
+			continue
+		}
+
+		if _, found := spec["f:healthCheckNodePort"]; found {
+			healthCheck = true
+		}
+
+		managedPorts, _ := spec["f:ports"].(map[string]interface{})
+		for key, fields := range managedPorts {
+			// items of lists with keys are "k:{"port":80,"protocol":"TCP"}"
+			port := make(map[string]interface{})
+			if !strings.HasPrefix(key, "k:") || json.Unmarshal([]byte(strings.TrimPrefix(key, "k:")), &port) != nil {
+				continue
+			}
+
+			if fields, ok := fields.(map[string]interface{}); ok && fields["f:nodePort"] != nil {
+				ports[servicePortKey(port)] = true
+			}
+		}
+	}
+
+	var applied map[string]interface{}
+	if json.Unmarshal([]byte(m.Annotations()[lastAppliedAnnotation]), &applied) == nil {
+		if spec, ok := nestedMap(applied, "spec"); ok && spec["healthCheckNodePort"] != nil {
+			healthCheck = true
+		}
+
+		for _, port := range nestedMaps(applied, "spec", "ports") {
+			if port["nodePort"] != nil {
+				ports[servicePortKey(port)] = true
+			}
+		}
+	}
+
+	return ports, healthCheck
+}
+
+// servicePortKey returns "port/protocol" of a Service port, the protocol is TCP by default
+func servicePortKey(port map[string]interface{}) string {
+	protocol := getFieldString(port, "protocol")
+	if protocol == "" {
+		protocol = "TCP"
+	}
+
+	return fmt.Sprintf("%v/%s", normalizeNumbers(port["port"]), protocol)
+}
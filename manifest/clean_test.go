@@ -0,0 +1,182 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const liveYAML = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+  uid: 6d1c1a7e-8f1b-4a3c-9a36-0f5d3c1e2b11
+  resourceVersion: "1024"
+  creationTimestamp: "2024-01-01T00:00:00Z"
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"kind":"Service"}'
+  labels:
+    app: web
+    team: core
+  managedFields:
+  - manager: kubectl
+    operation: Apply
+spec:
+  clusterIP: 10.96.0.10
+  clusterIPs:
+  - 10.96.0.10
+  ports:
+  - port: 80
+status:
+  loadBalancer: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: headless
+spec:
+  clusterIP: None
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  annotations:
+    pv.kubernetes.io/bind-completed: "yes"
+    backup: daily
+spec:
+  volumeName: pvc-1
+  resources:
+    requests:
+      storage: 1Gi
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  ownerReferences:
+  - apiVersion: batch/v1
+    kind: CronJob
+    name: migrate
+    uid: 1
+spec:
+  selector:
+    matchLabels:
+      batch.kubernetes.io/controller-uid: 1
+  template:
+    metadata:
+      labels:
+        batch.kubernetes.io/controller-uid: 1
+        job-name: migrate
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: ingress
+  managedFields:
+  - manager: helm
+    operation: Update
+    fieldsV1:
+      f:spec:
+        f:type: {}
+        f:ports:
+          k:{"port":80,"protocol":"TCP"}:
+            f:port: {}
+            f:nodePort: {}
+          k:{"port":443,"protocol":"TCP"}:
+            f:port: {}
+spec:
+  type: LoadBalancer
+  healthCheckNodePort: 32000
+  ports:
+  - port: 80
+    nodePort: 30080
+  - port: 443
+    protocol: TCP
+    nodePort: 31443
+`
+
+func Test_Cleaner(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(liveYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	objs := make([]unstructured.Unstructured, 0, len(manifests))
+	for _, m := range manifests {
+		objs = append(objs, *m.Unstructured())
+	}
+
+	cleaner := Cleaner{
+		Paths:     []string{"metadata.labels.team"},
+		KindPaths: map[string][]string{"PersistentVolumeClaim": {"spec.resources"}},
+	}
+
+	cleaned, err := cleaner.CleanList(objs)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	expected, err := ListFromYamlDocs(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: headless
+spec:
+  clusterIP: None
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  annotations:
+    backup: daily
+spec: {}
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  template:
+    metadata:
+      labels:
+        job-name: migrate
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: ingress
+spec:
+  type: LoadBalancer
+  ports:
+  - port: 80
+    nodePort: 30080
+  - port: 443
+    protocol: TCP
+`)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(cleaned).Should(HaveLen(len(expected)))
+
+	for i := range expected {
+		g.Expect(cleaned[i]).Should(Equal(expected[i]), "manifest %d", i)
+	}
+
+	g.Expect(objs[0].GetUID()).ShouldNot(BeEmpty(), "live objects are not changed")
+
+	_, err = Cleaner{Paths: []string{"metadata.name.first"}}.Clean(&objs[0])
+	g.Expect(err).Should(HaveOccurred())
+}
@@ -0,0 +1,128 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+// Converter converts manifests to typed API objects and back using types registered in the scheme
+type Converter struct {
+	scheme *runtime.Scheme
+	// Strict makes conversion to typed objects fail with UnknownFieldsError
+	// if a manifest has fields missing in the typed object
+	Strict bool
+}
+
+var defaultConverter = &Converter{scheme: clientgoscheme.Scheme}
+
+// NewConverter returns a converter for client-go types and types added by the functions,
+// e.g. AddToScheme functions of API packages of custom resources
+func NewConverter(addToScheme ...func(*runtime.Scheme) error) (*Converter, error) {
+	scheme := runtime.NewScheme()
+
+	for _, add := range append([]func(*runtime.Scheme) error{clientgoscheme.AddToScheme}, addToScheme...) {
+		if err := add(scheme); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Converter{scheme: scheme}, nil
+}
+
+// Scheme returns the scheme of the converter, more types can be added to it
+func (c *Converter) Scheme() *runtime.Scheme {
+	return c.scheme
+}
+
+// UnknownFieldsError lists manifest fields missing in the typed object
+type UnknownFieldsError struct {
+	Id     string
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("%s has unknown fields: %s", e.Id, strings.Join(e.Fields, ", "))
+}
+
+// ToTyped converts the manifest to a typed object of the registered type for its apiVersion and kind
+func (c *Converter) ToTyped(m Manifest) (runtime.Object, error) {
+	gvk := schema.FromAPIVersionAndKind(m.ApiVersion(), m.Kind())
+
+	obj, err := c.scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.Id(), err)
+	}
+
+	err = c.Into(m, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// Into decodes the manifest into the typed object, obj must be a pointer
+func (c *Converter) Into(m Manifest, obj interface{}) error {
+	err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(m, obj, c.Strict)
+	if err == nil {
+		return nil
+	}
+
+	if strictErr, ok := runtime.AsStrictDecodingError(err); ok {
+		fieldsErr := &UnknownFieldsError{Id: m.Id()}
+
+		for _, fieldErr := range strictErr.Errors() {
+			fieldsErr.Fields = append(fieldsErr.Fields, strings.Trim(strings.TrimPrefix(fieldErr.Error(), "unknown field "), `"`))
+		}
+
+		return fieldsErr
+	}
+
+	return fmt.Errorf("%s: %w", m.Id(), err)
+}
+
+// FromTyped converts the typed object to a manifest. apiVersion and kind are taken
+// from the scheme if they are not set in the object.
+func (c *Converter) FromTyped(obj runtime.Object) (Manifest, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Manifest(content)
+
+	if m.ApiVersion() == "" || m.Kind() == "" {
+		gvks, _, err := c.scheme.ObjectKinds(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(gvks) == 0 {
+			return nil, errors.New("no kinds registered for the object")
+		}
+
+		m["apiVersion"], m["kind"] = gvks[0].ToAPIVersionAndKind()
+	}
+
+	// typed objects always have creationTimestamp, it is null for objects that are not stored yet
+	if ts, found := m.Metadata()["creationTimestamp"]; found && ts == nil {
+		delete(m.Metadata(), "creationTimestamp")
+	}
+
+	return m, nil
+}
+
+// ToTyped converts the manifest to a typed client-go object, see Converter.ToTyped
+func ToTyped(m Manifest) (runtime.Object, error) {
+	return defaultConverter.ToTyped(m)
+}
+
+// FromTyped converts the typed client-go object to a manifest, see Converter.FromTyped
+func FromTyped(obj runtime.Object) (Manifest, error) {
+	return defaultConverter.FromTyped(obj)
+}
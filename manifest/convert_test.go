@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type Widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Size              int `json:"size"`
+}
+
+func (w *Widget) DeepCopyObject() runtime.Object {
+	c := *w
+	w.ObjectMeta.DeepCopyInto(&c.ObjectMeta)
+
+	return &c
+}
+
+func Test_Convert_ToTyped(t *testing.T) {
+	g := NewWithT(t)
+
+	obj, err := ToTyped(MustFromYAML(deploymentYAML))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	deployment, ok := obj.(*appsv1.Deployment)
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(*deployment.Spec.Replicas).Should(Equal(int32(2)))
+	g.Expect(deployment.Spec.Template.Spec.Containers).Should(HaveLen(2))
+
+	_, err = ToTyped(New("example.com/v1", "Widget", "foo"))
+	g.Expect(err).Should(MatchError(ContainSubstring("no kind \"Widget\" is registered")))
+}
+
+func Test_Convert_Strict(t *testing.T) {
+	g := NewWithT(t)
+
+	m := MustFromYAML(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+  labelz:
+    a: b
+data:
+  a: "1"
+spec:
+  x: 1
+`)
+
+	converter, err := NewConverter()
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	_, err = converter.ToTyped(m)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	converter.Strict = true
+
+	_, err = converter.ToTyped(m)
+	g.Expect(err).Should(Equal(&UnknownFieldsError{Id: "default/ConfigMap/foo", Fields: []string{"metadata.labelz", "spec"}}))
+}
+
+func Test_Convert_FromTyped(t *testing.T) {
+	g := NewWithT(t)
+
+	m, err := FromTyped(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+		Data:       map[string]string{"a": "1"},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(m).Should(Equal(MustFromYAML(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: foo
+  namespace: bar
+data:
+  a: "1"
+`)))
+
+	gv := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	converter, err := NewConverter(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(gv, &Widget{})
+		return nil
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	m, err = converter.FromTyped(&Widget{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Size: 3})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(m.ApiVersion()).Should(Equal("example.com/v1"))
+	g.Expect(m.Kind()).Should(Equal("Widget"))
+
+	obj, err := converter.ToTyped(m)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(obj.(*Widget).Size).Should(Equal(3))
+}
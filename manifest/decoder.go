@@ -0,0 +1,326 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Document is a YAML document read from a multi-document stream with its position
+type Document struct {
+	// Manifest is nil if the document has no content, e.g. it is empty or has only comments
+	Manifest Manifest
+	Source   Source
+	// Raw is the document content
+	Raw []byte
+}
+
+// Decoder reads YAML documents one by one from a stream. Documents are separated by "---"
+// and ended by "..." markers at the beginning of a line, as the YAML spec defines,
+// so indented "---" inside block scalars doesn't split documents.
+type Decoder struct {
+	r    *bufio.Reader
+	file string
+
+	index int
+	// line is the number of lines read
+	line int
+	// explicit is true if the next document is started with "---"
+	explicit bool
+	// pending is the content after "---" on the separator line
+	pending []byte
+	eof     bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next returns the next document or io.EOF if the stream is over. A document that is not
+// a valid YAML object is returned along with a ParseError, so decoding can be continued.
+func (d *Decoder) Next() (*Document, error) {
+	if d.eof {
+		return nil, io.EOF
+	}
+
+	var buf bytes.Buffer
+
+	explicit := d.explicit
+	start := d.line + 1
+
+	if d.pending != nil {
+		buf.Write(d.pending)
+		start = d.line
+		d.pending = nil
+	}
+
+	for {
+		line, err := d.r.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		if line != "" {
+			d.line++
+
+			marker, rest := documentMarker(line)
+
+			switch {
+			case marker == "---" && (explicit || hasContent(buf.Bytes())):
+				d.explicit = true
+				d.pending = rest
+
+				return d.document(buf.Bytes(), start, d.line-1)
+			case marker == "---":
+				// the first document is started explicitly
+				buf.Reset()
+				buf.Write(rest)
+
+				explicit = true
+				start = d.line + 1
+
+				if rest != nil {
+					start = d.line
+				}
+			case marker == "...":
+				d.explicit = false
+				return d.document(buf.Bytes(), start, d.line-1)
+			default:
+				buf.WriteString(line)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			d.eof = true
+
+			if !explicit && !hasContent(buf.Bytes()) {
+				return nil, io.EOF
+			}
+
+			return d.document(buf.Bytes(), start, d.line)
+		}
+	}
+}
+
+func (d *Decoder) document(raw []byte, start, end int) (*Document, error) {
+	doc := &Document{
+		Source: Source{
+			File:      d.file,
+			Template:  sourceTemplate(raw),
+			Index:     d.index,
+			StartLine: start,
+			EndLine:   end,
+		},
+		Raw: append([]byte(nil), raw...),
+	}
+	d.index++
+
+	if !hasContent(raw) {
+		return doc, nil
+	}
+
+	m, err := unmarshalManifest(raw)
+	if err != nil {
+		return doc, newParseError(doc.Source, err)
+	}
+
+	doc.Manifest = m
+
+	return doc, nil
+}
+
+// documentMarker returns "---" or "..." if the line is a document marker and the content after the marker
+func documentMarker(line string) (string, []byte) {
+	line = strings.TrimRight(line, "\r\n")
+
+	for _, marker := range []string{"---", "..."} {
+		if !strings.HasPrefix(line, marker) {
+			continue
+		}
+
+		rest := line[len(marker):]
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+
+		rest = strings.TrimSpace(rest)
+		if marker == "..." || rest == "" || strings.HasPrefix(rest, "#") {
+			return marker, nil
+		}
+
+		return marker, []byte(rest + "\n")
+	}
+
+	return "", nil
+}
+
+// hasContent is true if the document has lines other than blank lines and comments
+func hasContent(raw []byte) bool {
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != '#' {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReadAll returns all documents of the stream
+func ReadAll(r io.Reader) ([]*Document, error) {
+	var docs []*Document
+
+	d := NewDecoder(r)
+
+	for {
+		doc, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+}
+
+// stdin is replaced in tests
+var stdin io.Reader = os.Stdin
+
+// WalkFiles decodes documents of the files in order and calls fn for each document.
+// A path can be a file, a directory, a glob pattern or "-" for stdin. Directories are walked
+// recursively in lexical order and only files with .yaml, .yml or .json extension are read.
+func WalkFiles(fn func(doc *Document) error, paths ...string) error {
+	for _, path := range paths {
+		files, err := expandPath(path)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			err = walkFile(file, fn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReadFiles returns all documents of the files, see WalkFiles
+func ReadFiles(paths ...string) ([]*Document, error) {
+	var docs []*Document
+
+	err := WalkFiles(func(doc *Document) error {
+		docs = append(docs, doc)
+		return nil
+	}, paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func walkFile(file string, fn func(doc *Document) error) error {
+	var r io.Reader
+
+	if file == "-" {
+		r = stdin
+	} else {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = f.Close() }()
+
+		r = f
+	}
+
+	d := NewDecoder(r)
+	d.file = file
+
+	for {
+		doc, err := d.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = fn(doc)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// expandPath returns files for a file, directory or glob path
+func expandPath(path string) ([]string, error) {
+	if path == "-" {
+		return []string{path}, nil
+	}
+
+	matches := []string{path}
+
+	if strings.ContainsAny(path, "*?[") {
+		var err error
+
+		matches, err = filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", path)
+		}
+	}
+
+	var files []string
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+
+		err = filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			switch strings.ToLower(filepath.Ext(p)) {
+			case ".yaml", ".yml", ".json":
+				files = append(files, p)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,113 @@
+package manifest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const multiDocYAML = `# leading comment
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: script
+data:
+  run.sh: |
+    echo start
+    ---
+    echo end
+---
+# only a comment
+---
+--- {"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "inline"}}
+...
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+
+func Test_Decoder(t *testing.T) {
+	g := NewWithT(t)
+
+	docs, err := ReadAll(strings.NewReader(multiDocYAML))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(docs).Should(HaveLen(5))
+
+	type position struct {
+		Index, StartLine, EndLine int
+		Name                      string
+	}
+
+	var positions []position
+	for _, doc := range docs {
+		positions = append(positions, position{doc.Source.Index, doc.Source.StartLine, doc.Source.EndLine, doc.Manifest.Name()})
+	}
+
+	g.Expect(positions).Should(Equal([]position{
+		{0, 3, 11, "script"},
+		{1, 13, 13, ""},
+		{2, 15, 14, ""},
+		{3, 15, 15, "inline"},
+		{4, 17, 20, "web"},
+	}))
+
+	data, _, _ := docs[0].Manifest.GetString(`data["run.sh"]`)
+	g.Expect(data).Should(Equal("echo start\n---\necho end\n"))
+}
+
+func Test_Decoder_Error(t *testing.T) {
+	g := NewWithT(t)
+
+	d := NewDecoder(strings.NewReader("a: [1\n---\nkind: ConfigMap\n"))
+
+	doc, err := d.Next()
+	g.Expect(err).Should(MatchError("line 1: document 0: did not find expected ',' or ']'"))
+	g.Expect(doc.Manifest).Should(BeNil())
+
+	doc, err = d.Next()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(doc.Manifest.Kind()).Should(Equal("ConfigMap"))
+
+	_, err = d.Next()
+	g.Expect(errors.Is(err, io.EOF)).Should(BeTrue())
+}
+
+func Test_ReadFiles(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.MkdirAll(filepath.Join(dir, "sub"), 0o755)).Should(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "a.yaml"), []byte("kind: A\n---\nkind: B\n"), 0o600)).Should(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "sub", "c.json"), []byte(`{"kind": "C"}`), 0o600)).Should(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "sub", "README.md"), []byte("# readme"), 0o600)).Should(Succeed())
+
+	stdin = strings.NewReader("kind: D\n")
+	defer func() { stdin = os.Stdin }()
+
+	kinds := func(docs []*Document) []string {
+		var res []string
+		for _, doc := range docs {
+			res = append(res, doc.Manifest.Kind()+"@"+filepath.Base(doc.Source.File))
+		}
+
+		return res
+	}
+
+	docs, err := ReadFiles(dir, "-")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(kinds(docs)).Should(Equal([]string{"A@a.yaml", "B@a.yaml", "C@c.json", "D@-"}))
+
+	docs, err = ReadFiles(filepath.Join(dir, "*", "*.json"))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(kinds(docs)).Should(Equal([]string{"C@c.json"}))
+
+	_, err = ReadFiles(filepath.Join(dir, "*.yml"))
+	g.Expect(err).Should(MatchError(ContainSubstring("no files match")))
+}
@@ -0,0 +1,314 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeType is a kind of change of an object or a field
+type ChangeType string
+
+const (
+	Added    ChangeType = "added"
+	Removed  ChangeType = "removed"
+	Modified ChangeType = "modified"
+)
+
+// FieldChange is a changed field of an object, Old is nil for added fields and New is nil for removed ones
+type FieldChange struct {
+	Path string
+	Type ChangeType
+	Old  interface{}
+	New  interface{}
+}
+
+// ObjectDiff is an added, removed or modified object. Old and New are redacted, see DiffOptions.Redactor.
+type ObjectDiff struct {
+	Key  ObjectKey
+	Type ChangeType
+	Old  Manifest
+	New  Manifest
+	// Changes are fields of a modified object in path order
+	Changes []FieldChange
+}
+
+// ListDiff is the difference between two manifest lists, objects are sorted by keys
+type ListDiff struct {
+	Objects []ObjectDiff
+}
+
+// DiffOptions changes what is compared and how values are shown
+type DiffOptions struct {
+	// IgnorePaths are fields removed from both manifests before comparison, e.g. "status" or "spec.replicas"
+	IgnorePaths []string
+	// IgnoreAnnotations are annotation keys removed from both manifests before comparison
+	IgnoreAnnotations []string
+	// Redactor masks sensitive values of results, Secret values are masked by default.
+	// Objects are compared before redaction, so changes of masked values are reported too.
+	Redactor Redactor
+}
+
+// DiffLists compares manifests of two lists by their keys, versions are not a part of keys,
+// so an apiVersion change is a field change. Lists with duplicates are not compared.
+func DiffLists(oldList, newList ManifestList, opts DiffOptions) (*ListDiff, error) {
+	for _, l := range []ManifestList{oldList, newList} {
+		if err := l.CheckDuplicates(); err != nil {
+			return nil, err
+		}
+	}
+
+	oldObjects, err := opts.prepare(oldList)
+	if err != nil {
+		return nil, err
+	}
+
+	newObjects, err := opts.prepare(newList)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ListDiff{}
+
+	for id, o := range oldObjects {
+		n, found := newObjects[id]
+		if !found {
+			diff.Objects = append(diff.Objects, ObjectDiff{Key: o.key, Type: Removed, Old: opts.Redactor.Redact(o.original)})
+			continue
+		}
+
+		changes := diffValues(nil, map[string]interface{}(o.compared), map[string]interface{}(n.compared), nil)
+		if len(changes) == 0 {
+			continue
+		}
+
+		objDiff := ObjectDiff{
+			Key:     n.key,
+			Type:    Modified,
+			Old:     opts.Redactor.Redact(o.original),
+			New:     opts.Redactor.Redact(n.original),
+			Changes: changes,
+		}
+		objDiff.redactChanges()
+
+		diff.Objects = append(diff.Objects, objDiff)
+	}
+
+	for id, n := range newObjects {
+		if _, found := oldObjects[id]; !found {
+			diff.Objects = append(diff.Objects, ObjectDiff{Key: n.key, Type: Added, New: opts.Redactor.Redact(n.original)})
+		}
+	}
+
+	sort.Slice(diff.Objects, func(i, j int) bool {
+		return diff.Objects[i].Key.String() < diff.Objects[j].Key.String()
+	})
+
+	return diff, nil
+}
+
+// diffObject is a manifest prepared for comparison
+type diffObject struct {
+	key      ObjectKey
+	original Manifest
+	compared Manifest
+}
+
+func (opts DiffOptions) prepare(l ManifestList) (map[schemaKey]diffObject, error) {
+	res := make(map[schemaKey]diffObject, len(l))
+
+	for _, m := range l {
+		compared := Manifest(normalizeNumbers(map[string]interface{}(m.DeepCopy())).(map[string]interface{}))
+
+		for _, path := range opts.IgnorePaths {
+			if _, err := compared.Remove(path); err != nil {
+				return nil, fmt.Errorf("%s: %w", m.ObjectKey(), err)
+			}
+		}
+
+		if len(opts.IgnoreAnnotations) > 0 {
+			annotations := compared.Annotations()
+			for _, key := range opts.IgnoreAnnotations {
+				delete(annotations, key)
+			}
+
+			compared.SetAnnotations(annotations)
+		}
+
+		key := m.ObjectKey()
+		res[schemaKey{key.GroupKind(), key.Namespace, key.Name}] = diffObject{key: key, original: m, compared: compared}
+	}
+
+	return res, nil
+}
+
+// redactChanges replaces values of changes with values of redacted manifests
+func (d *ObjectDiff) redactChanges() {
+	for i := range d.Changes {
+		change := &d.Changes[i]
+
+		if change.Old != nil {
+			change.Old, _, _ = d.Old.Get(change.Path)
+		}
+
+		if change.New != nil {
+			change.New, _, _ = d.New.Get(change.Path)
+		}
+	}
+}
+
+// diffValues compares maps by keys and lists by indexes
+func diffValues(elems []pathElement, a, b interface{}, changes []FieldChange) []FieldChange {
+	path := func(e pathElement) []pathElement {
+		return append(elems[:len(elems):len(elems)], e)
+	}
+	field := func(elems []pathElement) string {
+		return strings.TrimPrefix(formatPath(elems), ".")
+	}
+
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		keys := sortedKeys(a)
+		for _, k := range sortedKeys(b) {
+			if _, found := a[k]; !found {
+				keys = append(keys, k)
+			}
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			av, inA := a[k]
+			bv, inB := b[k]
+
+			switch {
+			case !inA:
+				changes = append(changes, FieldChange{Path: field(path(pathElement{key: k})), Type: Added, New: bv})
+			case !inB:
+				changes = append(changes, FieldChange{Path: field(path(pathElement{key: k})), Type: Removed, Old: av})
+			default:
+				changes = diffValues(path(pathElement{key: k}), av, bv, changes)
+			}
+		}
+
+		return changes
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+
+		for i := 0; i < max(len(a), len(b)); i++ {
+			e := pathElement{index: i, isIndex: true}
+
+			switch {
+			case i >= len(a):
+				changes = append(changes, FieldChange{Path: field(path(e)), Type: Added, New: b[i]})
+			case i >= len(b):
+				changes = append(changes, FieldChange{Path: field(path(e)), Type: Removed, Old: a[i]})
+			default:
+				changes = diffValues(path(e), a[i], b[i], changes)
+			}
+		}
+
+		return changes
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return changes
+	}
+
+	return append(changes, FieldChange{Path: field(elems), Type: Modified, Old: a, New: b})
+}
+
+// Empty is true if the lists have the same objects
+func (d *ListDiff) Empty() bool {
+	return len(d.Objects) == 0
+}
+
+// Filter returns objects with the change type
+func (d *ListDiff) Filter(typ ChangeType) []ObjectDiff {
+	var res []ObjectDiff
+
+	for _, obj := range d.Objects {
+		if obj.Type == typ {
+			res = append(res, obj)
+		}
+	}
+
+	return res
+}
+
+var changeMarks = map[ChangeType]string{
+	Added:    "+",
+	Removed:  "-",
+	Modified: "~",
+}
+
+// Render writes the diff in a human-readable form, objects are marked with "+", "-" or "~"
+// and field changes of modified objects are indented, values are JSON:
+//
+//	~ ConfigMap/prod/settings
+//	    ~ data.mode: "safe" -> "fast"
+//	    + metadata.labels.team: "core"
+//	+ Deployment.apps/prod/web
+//	- Secret/prod/old
+func (d *ListDiff) Render(w io.Writer) error {
+	for _, obj := range d.Objects {
+		_, err := fmt.Fprintf(w, "%s %s\n", changeMarks[obj.Type], obj.Key)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range obj.Changes {
+			var line string
+
+			switch change.Type {
+			case Added:
+				line = fmt.Sprintf("%s: %s", change.Path, renderValue(change.New))
+			case Removed:
+				line = fmt.Sprintf("%s: %s", change.Path, renderValue(change.Old))
+			default:
+				line = fmt.Sprintf("%s: %s -> %s", change.Path, renderValue(change.Old), renderValue(change.New))
+			}
+
+			_, err = fmt.Fprintf(w, "    %s %s\n", changeMarks[change.Type], line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *ListDiff) String() string {
+	var sb strings.Builder
+
+	_ = d.Render(&sb)
+
+	return sb.String()
+}
+
+// renderValue returns the value as compact JSON
+func renderValue(value interface{}) string {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(value); err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const diffOldYAML = `
+apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+  annotations:
+    deployed-at: "1"
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+      - name: sidecar
+        image: sidecar:1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: prod
+data:
+  password: b2xk
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: old
+  namespace: prod
+`
+
+const diffNewYAML = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: new
+  namespace: prod
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+  annotations:
+    deployed-at: "2"
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  replicas: 2.0
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  namespace: prod
+data:
+  password: bmV3
+`
+
+func Test_DiffLists(t *testing.T) {
+	g := NewWithT(t)
+
+	oldList, err := ListFromYamlDocs(diffOldYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	newList, err := ListFromYamlDocs(diffNewYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	diff, err := DiffLists(oldList, newList, DiffOptions{IgnoreAnnotations: []string{"deployed-at"}})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(diff.Filter(Added)).Should(HaveLen(1))
+	g.Expect(diff.Filter(Removed)).Should(HaveLen(1))
+	g.Expect(diff.Filter(Modified)).Should(HaveLen(2))
+
+	g.Expect(diff.String()).Should(Equal(`+ ConfigMap/prod/new
+- ConfigMap/prod/old
+~ Deployment.apps/prod/web
+    ~ apiVersion: "apps/v1beta2" -> "apps/v1"
+    + metadata.labels: {"app.kubernetes.io/name":"web"}
+    ~ spec.replicas: 1 -> 2
+    - spec.template.spec.containers[1]: {"image":"sidecar:1","name":"sidecar"}
+~ Secret/prod/creds
+    ~ data.password: "<redacted>" -> "<redacted>"
+`))
+
+	diff, err = DiffLists(oldList, newList, DiffOptions{
+		IgnorePaths:       []string{"apiVersion", "metadata.labels", "spec"},
+		IgnoreAnnotations: []string{"deployed-at"},
+		Redactor:          Redactor{Hash: true},
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	modified := diff.Filter(Modified)
+	g.Expect(modified).Should(HaveLen(1))
+	g.Expect(modified[0].Changes[0].Old).ShouldNot(Equal(modified[0].Changes[0].New))
+	g.Expect(modified[0].Changes[0].Old).Should(HavePrefix("<redacted sha256:"))
+
+	_, err = DiffLists(append(oldList, oldList[0]), newList, DiffOptions{})
+	g.Expect(err).Should(MatchError(ContainSubstring("duplicate manifest Deployment.apps/prod/web")))
+
+	diff, err = DiffLists(newList, newList, DiffOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(diff.Empty()).Should(BeTrue())
+}
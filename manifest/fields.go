@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (m Manifest) SetName(name string) {
+	m.Unstructured().SetName(name)
+}
+
+func (m Manifest) GenerateName() string {
+	return getFieldString(m.Metadata(), "generateName")
+}
+
+func (m Manifest) SetGenerateName(generateName string) {
+	m.Unstructured().SetGenerateName(generateName)
+}
+
+// Labels returns a copy of metadata.labels, it is nil if labels are missing or malformed
+func (m Manifest) Labels() map[string]string {
+	return m.Unstructured().GetLabels()
+}
+
+// SetLabels replaces metadata.labels, empty labels remove the field
+func (m Manifest) SetLabels(labels map[string]string) {
+	m.Unstructured().SetLabels(labels)
+}
+
+func (m Manifest) SetLabel(key, value string) {
+	labels := m.Labels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	labels[key] = value
+	m.SetLabels(labels)
+}
+
+// Annotations returns a copy of metadata.annotations, it is nil if annotations are missing or malformed
+func (m Manifest) Annotations() map[string]string {
+	return m.Unstructured().GetAnnotations()
+}
+
+// SetAnnotations replaces metadata.annotations, empty annotations remove the field
+func (m Manifest) SetAnnotations(annotations map[string]string) {
+	m.Unstructured().SetAnnotations(annotations)
+}
+
+func (m Manifest) SetAnnotation(key, value string) {
+	annotations := m.Annotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+
+	annotations[key] = value
+	m.SetAnnotations(annotations)
+}
+
+func (m Manifest) OwnerReferences() []metav1.OwnerReference {
+	return m.Unstructured().GetOwnerReferences()
+}
+
+func (m Manifest) SetOwnerReferences(refs []metav1.OwnerReference) {
+	m.Unstructured().SetOwnerReferences(refs)
+}
+
+func (m Manifest) Finalizers() []string {
+	return m.Unstructured().GetFinalizers()
+}
+
+func (m Manifest) SetFinalizers(finalizers []string) {
+	m.Unstructured().SetFinalizers(finalizers)
+}
+
+// AddFinalizer adds the finalizer if it is missing, it is false if the finalizer is already present
+func (m Manifest) AddFinalizer(finalizer string) bool {
+	finalizers := m.Finalizers()
+	for _, f := range finalizers {
+		if f == finalizer {
+			return false
+		}
+	}
+
+	m.SetFinalizers(append(finalizers, finalizer))
+
+	return true
+}
+
+// RemoveFinalizer removes the finalizer, it is false if the finalizer is missing
+func (m Manifest) RemoveFinalizer(finalizer string) bool {
+	finalizers := m.Finalizers()
+	res := make([]string, 0, len(finalizers))
+
+	for _, f := range finalizers {
+		if f != finalizer {
+			res = append(res, f)
+		}
+	}
+
+	if len(res) == len(finalizers) {
+		return false
+	}
+
+	m.SetFinalizers(res)
+
+	return true
+}
@@ -0,0 +1,30 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_Metadata_Fields(t *testing.T) {
+	g := NewWithT(t)
+	m := New("v1", "ConfigMap", "foo")
+
+	m.SetLabel("app", "web")
+	m.SetAnnotation("checksum", "abc")
+	m.SetGenerateName("foo-")
+	m.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "1"}})
+
+	g.Expect(m.Labels()).Should(Equal(map[string]string{"app": "web"}))
+	g.Expect(m.Annotations()).Should(Equal(map[string]string{"checksum": "abc"}))
+	g.Expect(m.GenerateName()).Should(Equal("foo-"))
+	g.Expect(m.OwnerReferences()).Should(HaveLen(1))
+
+	g.Expect(m.AddFinalizer("example.com/cleanup")).Should(BeTrue())
+	g.Expect(m.AddFinalizer("example.com/cleanup")).Should(BeFalse())
+	g.Expect(m.Finalizers()).Should(Equal([]string{"example.com/cleanup"}))
+	g.Expect(m.RemoveFinalizer("example.com/cleanup")).Should(BeTrue())
+	g.Expect(m.RemoveFinalizer("example.com/cleanup")).Should(BeFalse())
+	g.Expect(m.Finalizers()).Should(BeEmpty())
+}
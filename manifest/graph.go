@@ -0,0 +1,323 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	coreV1 = schema.GroupVersion{Version: "v1"}
+	rbacV1 = schema.GroupVersion{Group: "rbac.authorization.k8s.io", Version: "v1"}
+)
+
+// ingressKinds are served by networking.k8s.io and by extensions in old clusters
+var ingressKinds = map[schema.GroupKind]bool{
+	{Group: "networking.k8s.io", Kind: "Ingress"}: true,
+	{Group: "extensions", Kind: "Ingress"}:        true,
+}
+
+// Reference is a reference by name from one object to another
+type Reference struct {
+	From ObjectKey
+	// To has no version if the source doesn't define it, e.g. an Ingress resource backend
+	To ObjectKey
+	// Path is the field of the source with the name, e.g. "spec.template.spec.volumes[0].configMap.name"
+	Path string
+	// Optional is true for references with "optional: true", e.g. configMap volumes and envFrom
+	Optional bool
+}
+
+// Graph is a set of manifests with references between them and to objects outside the set
+type Graph struct {
+	Manifests  ManifestList
+	References []Reference
+
+	index map[schemaKey]int
+}
+
+// BuildGraph extracts references of the manifests:
+//
+//   - pod specs of workloads to ServiceAccounts, ConfigMaps, Secrets and PersistentVolumeClaims
+//   - role bindings to roles and ServiceAccount subjects
+//   - owner references
+//   - Ingress backends to Services and other resources, and TLS to Secrets
+//
+// Namespaces are used as is, call DefaultNamespaces first for manifests without a namespace.
+func BuildGraph(l ManifestList) *Graph {
+	g := &Graph{Manifests: l, index: make(map[schemaKey]int, len(l))}
+
+	for i, m := range l {
+		key := m.ObjectKey()
+		if _, found := g.index[schemaKey{key.GroupKind(), key.Namespace, key.Name}]; !found {
+			g.index[schemaKey{key.GroupKind(), key.Namespace, key.Name}] = i
+		}
+
+		g.References = append(g.References, manifestRefs(m)...)
+	}
+
+	return g
+}
+
+func manifestRefs(m Manifest) []Reference {
+	from := m.ObjectKey()
+
+	var refs []Reference
+
+	add := func(gv schema.GroupVersion, kind, namespace, name, path string, optional bool) {
+		if name == "" {
+			return
+		}
+
+		refs = append(refs, Reference{
+			From:     from,
+			To:       ObjectKey{GroupVersionKind: gv.WithKind(kind), Namespace: namespace, Name: name},
+			Path:     path,
+			Optional: optional,
+		})
+	}
+
+	if template, ok := m.podTemplate(); ok {
+		path, _ := m.podTemplatePath()
+		prefix := strings.Join(append(append([]string(nil), path...), "spec"), ".") + "."
+
+		if podSpec, ok := nestedMap(template, "spec"); ok {
+			for _, ref := range podSpecRefs(podSpec) {
+				add(coreV1, ref.kind, from.Namespace, ref.name(), prefix+ref.path, ref.optional())
+			}
+		}
+	}
+
+	for i, owner := range nestedMaps(m, "metadata", "ownerReferences") {
+		gv, _ := schema.ParseGroupVersion(getFieldString(owner, "apiVersion"))
+		// owners are in the namespace of the object or cluster-scoped, see Graph.find
+		add(gv, getFieldString(owner, "kind"), from.Namespace, getFieldString(owner, "name"),
+			fmt.Sprintf("metadata.ownerReferences[%d].name", i), false)
+	}
+
+	switch {
+	case bindingKinds[from.GroupKind()]:
+		if roleRef, ok := nestedMap(m, "roleRef"); ok {
+			kind := getFieldString(roleRef, "kind")
+
+			namespace := from.Namespace
+			if kind == "ClusterRole" {
+				namespace = ""
+			}
+
+			add(rbacV1, kind, namespace, getFieldString(roleRef, "name"), "roleRef.name", false)
+		}
+
+		for i, subject := range nestedMaps(m, "subjects") {
+			if getFieldString(subject, "kind") != "ServiceAccount" {
+				continue
+			}
+
+			namespace := getFieldString(subject, "namespace")
+			if namespace == "" {
+				namespace = from.Namespace
+			}
+
+			add(coreV1, "ServiceAccount", namespace, getFieldString(subject, "name"), fmt.Sprintf("subjects[%d].name", i), false)
+		}
+	case ingressKinds[from.GroupKind()]:
+		backend := func(obj map[string]interface{}, path string) {
+			if service, ok := nestedMap(obj, "service"); ok {
+				add(coreV1, "Service", from.Namespace, getFieldString(service, "name"), path+".service.name", false)
+			}
+
+			// extensions/v1beta1 and networking.k8s.io/v1beta1
+			add(coreV1, "Service", from.Namespace, getFieldString(obj, "serviceName"), path+".serviceName", false)
+
+			if resource, ok := nestedMap(obj, "resource"); ok {
+				gv := schema.GroupVersion{Group: getFieldString(resource, "apiGroup")}
+				add(gv, getFieldString(resource, "kind"), from.Namespace, getFieldString(resource, "name"), path+".resource.name", false)
+			}
+		}
+
+		for _, field := range []string{"defaultBackend", "backend"} {
+			if obj, ok := nestedMap(m, "spec", field); ok {
+				backend(obj, "spec."+field)
+			}
+		}
+
+		for i, rule := range nestedMaps(m, "spec", "rules") {
+			for j, path := range nestedMaps(rule, "http", "paths") {
+				if obj, ok := nestedMap(path, "backend"); ok {
+					backend(obj, fmt.Sprintf("spec.rules[%d].http.paths[%d].backend", i, j))
+				}
+			}
+		}
+
+		for i, tls := range nestedMaps(m, "spec", "tls") {
+			add(coreV1, "Secret", from.Namespace, getFieldString(tls, "secretName"), fmt.Sprintf("spec.tls[%d].secretName", i), false)
+		}
+	}
+
+	return refs
+}
+
+// find returns the index of the target in the set, versions are not compared.
+// A namespaced target is also looked up as cluster-scoped, e.g. an owner of a namespaced object
+// can be cluster-scoped.
+func (g *Graph) find(to ObjectKey) (int, bool) {
+	i, found := g.index[schemaKey{to.GroupKind(), to.Namespace, to.Name}]
+	if !found && to.Namespace != "" {
+		i, found = g.index[schemaKey{to.GroupKind(), "", to.Name}]
+	}
+
+	return i, found
+}
+
+// From returns references of the object
+func (g *Graph) From(key ObjectKey) []Reference {
+	var res []Reference
+
+	for _, ref := range g.References {
+		if ref.From.SameObject(key) {
+			res = append(res, ref)
+		}
+	}
+
+	return res
+}
+
+// To returns references to the object
+func (g *Graph) To(key ObjectKey) []Reference {
+	var res []Reference
+
+	for _, ref := range g.References {
+		if ref.To.SameObject(key) {
+			res = append(res, ref)
+		}
+	}
+
+	return res
+}
+
+// Missing returns references to objects that are not in the set. Optional references
+// and references to the "default" ServiceAccount, which is created in every namespace, are skipped.
+func (g *Graph) Missing() []Reference {
+	var res []Reference
+
+	for _, ref := range g.References {
+		if ref.Optional || (ref.To.Group == "" && ref.To.Kind == "ServiceAccount" && ref.To.Name == "default") {
+			continue
+		}
+
+		if _, found := g.find(ref.To); !found {
+			res = append(res, ref)
+		}
+	}
+
+	return res
+}
+
+// LiveCluster looks up objects in a cluster, *client.Client satisfies it
+type LiveCluster interface {
+	ScopeResolver
+	Dynamic() dynamic.Interface
+}
+
+// MissingInCluster returns references to objects that are neither in the set nor in the cluster, see Missing.
+// References without a version, e.g. Ingress resource backends, can't be looked up and are returned as is.
+func (g *Graph) MissingInCluster(ctx context.Context, cluster LiveCluster) ([]Reference, error) {
+	var res []Reference
+
+	for _, ref := range g.Missing() {
+		if ref.To.Version == "" {
+			res = append(res, ref)
+			continue
+		}
+
+		apiRes, err := cluster.APIResource(ref.To.GroupVersion().String(), ref.To.Kind)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.To, err)
+		}
+
+		gvr := ref.To.GroupVersion().WithResource(apiRes.Name)
+
+		namespace := ref.To.Namespace
+		if !apiRes.Namespaced {
+			namespace = ""
+		}
+
+		_, err = cluster.Dynamic().Resource(gvr).Namespace(namespace).Get(ctx, ref.To.Name, metav1.GetOptions{})
+
+		switch {
+		case apierrors.IsNotFound(err):
+			res = append(res, ref)
+		case err != nil:
+			return nil, fmt.Errorf("%s: %w", ref.To, err)
+		}
+	}
+
+	return res, nil
+}
+
+// ApplyOrder returns manifests of the set ordered so that referenced objects come before objects
+// that reference them, otherwise the list order is kept. References to objects outside the set are ignored.
+func (g *Graph) ApplyOrder() (ManifestList, error) {
+	deps := make([]map[int]bool, len(g.Manifests))
+
+	for _, ref := range g.References {
+		from, _ := g.find(ref.From)
+		to, found := g.find(ref.To)
+
+		if !found || from == to {
+			continue
+		}
+
+		if deps[from] == nil {
+			deps[from] = make(map[int]bool)
+		}
+
+		deps[from][to] = true
+	}
+
+	res := make(ManifestList, 0, len(g.Manifests))
+	done := make([]bool, len(g.Manifests))
+
+	for len(res) < len(g.Manifests) {
+		next := -1
+
+		for i := range g.Manifests {
+			if !done[i] && depsDone(deps[i], done) {
+				next = i
+				break
+			}
+		}
+
+		if next < 0 {
+			var cycle []string
+
+			for i, m := range g.Manifests {
+				if !done[i] {
+					cycle = append(cycle, m.ObjectKey().String())
+				}
+			}
+
+			return nil, fmt.Errorf("dependency cycle between %s", strings.Join(cycle, ", "))
+		}
+
+		done[next] = true
+		res = append(res, g.Manifests[next])
+	}
+
+	return res, nil
+}
+
+func depsDone(deps map[int]bool, done []bool) bool {
+	for i := range deps {
+		if !done[i] {
+			return false
+		}
+	}
+
+	return true
+}
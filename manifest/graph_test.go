@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const graphYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+spec:
+  template:
+    spec:
+      serviceAccountName: web
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: web-env
+        - secretRef:
+            name: web-secrets
+            optional: true
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-env
+  namespace: prod
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: web
+  namespace: prod
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+subjects:
+- kind: ServiceAccount
+  name: web
+- kind: User
+  name: admin
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: prod
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: web
+spec:
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          service:
+            name: web
+  tls:
+  - secretName: web-tls
+`
+
+func Test_BuildGraph(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(graphYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	graph := BuildGraph(manifests)
+
+	paths := func(refs []Reference) []string {
+		var res []string
+		for _, ref := range refs {
+			res = append(res, ref.Path+" -> "+ref.To.String())
+		}
+
+		return res
+	}
+
+	g.Expect(paths(graph.From(manifests[0].ObjectKey()))).Should(Equal([]string{
+		"spec.template.spec.serviceAccountName -> ServiceAccount/prod/web",
+		"spec.template.spec.volumes[0].persistentVolumeClaim.claimName -> PersistentVolumeClaim/prod/data",
+		"spec.template.spec.containers[0].envFrom[0].configMapRef.name -> ConfigMap/prod/web-env",
+		"spec.template.spec.containers[0].envFrom[1].secretRef.name -> Secret/prod/web-secrets",
+	}))
+	g.Expect(graph.From(manifests[0].ObjectKey())[3].Optional).Should(BeTrue())
+
+	g.Expect(paths(graph.From(manifests[3].ObjectKey()))).Should(Equal([]string{
+		"roleRef.name -> ClusterRole.rbac.authorization.k8s.io/view",
+		"subjects[0].name -> ServiceAccount/prod/web",
+	}))
+
+	g.Expect(paths(graph.To(manifests[1].ObjectKey()))).Should(HaveLen(2))
+
+	g.Expect(paths(graph.Missing())).Should(Equal([]string{
+		"spec.template.spec.volumes[0].persistentVolumeClaim.claimName -> PersistentVolumeClaim/prod/data",
+		"roleRef.name -> ClusterRole.rbac.authorization.k8s.io/view",
+		"spec.rules[0].http.paths[0].backend.service.name -> Service/prod/web",
+		"spec.tls[0].secretName -> Secret/prod/web-tls",
+	}))
+}
+
+func Test_Graph_ApplyOrder(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(graphYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	ordered, err := BuildGraph(manifests).ApplyOrder()
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	var keys []string
+	for _, m := range ordered {
+		keys = append(keys, m.ObjectKey().String())
+	}
+
+	g.Expect(keys).Should(Equal([]string{
+		"ServiceAccount/prod/web",
+		"ConfigMap/prod/web-env",
+		"Deployment.apps/prod/web",
+		"RoleBinding.rbac.authorization.k8s.io/prod/web",
+		"Ingress.networking.k8s.io/prod/web",
+	}))
+
+	a := New("v1", "ConfigMap", "a")
+	a.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "b"}})
+	b := New("v1", "ConfigMap", "b")
+	b.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "a"}})
+
+	_, err = BuildGraph(ManifestList{a, b}).ApplyOrder()
+	g.Expect(err).Should(MatchError("dependency cycle between ConfigMap/a, ConfigMap/b"))
+}
@@ -0,0 +1,219 @@
+package manifest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Helm annotations, see https://helm.sh/docs/topics/charts_hooks/
+const (
+	HookAnnotation             = "helm.sh/hook"
+	HookWeightAnnotation       = "helm.sh/hook-weight"
+	HookDeletePolicyAnnotation = "helm.sh/hook-delete-policy"
+	ResourcePolicyAnnotation   = "helm.sh/resource-policy"
+
+	// ResourcePolicyKeep keeps the object when the release is deleted
+	ResourcePolicyKeep = "keep"
+)
+
+// HookEvent is a release lifecycle event running a hook
+type HookEvent string
+
+const (
+	HookPreInstall   HookEvent = "pre-install"
+	HookPostInstall  HookEvent = "post-install"
+	HookPreDelete    HookEvent = "pre-delete"
+	HookPostDelete   HookEvent = "post-delete"
+	HookPreUpgrade   HookEvent = "pre-upgrade"
+	HookPostUpgrade  HookEvent = "post-upgrade"
+	HookPreRollback  HookEvent = "pre-rollback"
+	HookPostRollback HookEvent = "post-rollback"
+	HookTest         HookEvent = "test"
+)
+
+// hookEvents are known events, "test-success" is the Helm 2 name of "test"
+var hookEvents = map[string]HookEvent{
+	string(HookPreInstall):   HookPreInstall,
+	string(HookPostInstall):  HookPostInstall,
+	string(HookPreDelete):    HookPreDelete,
+	string(HookPostDelete):   HookPostDelete,
+	string(HookPreUpgrade):   HookPreUpgrade,
+	string(HookPostUpgrade):  HookPostUpgrade,
+	string(HookPreRollback):  HookPreRollback,
+	string(HookPostRollback): HookPostRollback,
+	string(HookTest):         HookTest,
+	"test-success":           HookTest,
+}
+
+// HookDeletePolicy defines when a hook object is deleted
+type HookDeletePolicy string
+
+const (
+	HookBeforeHookCreation HookDeletePolicy = "before-hook-creation"
+	HookSucceeded          HookDeletePolicy = "hook-succeeded"
+	HookFailed             HookDeletePolicy = "hook-failed"
+)
+
+// Hook is a parsed Helm hook of a manifest
+type Hook struct {
+	Events []HookEvent
+	// Weight orders hooks of an event, invalid weights are 0 as in Helm
+	Weight int
+	// DeletePolicies are before-hook-creation if the annotation is not set, as in Helm
+	DeletePolicies []HookDeletePolicy
+}
+
+// HasEvent is true if the hook runs on the event
+func (h *Hook) HasEvent(event HookEvent) bool {
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Hook returns the Helm hook of the manifest, it is false if the manifest is not a hook.
+// Events and delete policies are case-insensitive. As in Helm, a hook with an unknown event
+// is never run, so it is false too, see UnknownHooks.
+func (m Manifest) Hook() (*Hook, bool) {
+	annotations := m.Annotations()
+
+	value, ok := annotations[HookAnnotation]
+	if !ok {
+		return nil, false
+	}
+
+	hook := &Hook{}
+
+	for _, name := range splitAnnotation(value) {
+		event, known := hookEvents[name]
+		if !known {
+			return nil, false
+		}
+
+		hook.Events = append(hook.Events, event)
+	}
+
+	if len(hook.Events) == 0 {
+		return nil, false
+	}
+
+	hook.Weight, _ = strconv.Atoi(strings.TrimSpace(annotations[HookWeightAnnotation]))
+
+	for _, policy := range splitAnnotation(annotations[HookDeletePolicyAnnotation]) {
+		hook.DeletePolicies = append(hook.DeletePolicies, HookDeletePolicy(policy))
+	}
+
+	if len(hook.DeletePolicies) == 0 {
+		hook.DeletePolicies = []HookDeletePolicy{HookBeforeHookCreation}
+	}
+
+	return hook, true
+}
+
+// IsHook is true if the manifest has the Helm hook annotation. Hooks with unknown events
+// are hooks too: Helm skips them and never installs them as resources.
+func (m Manifest) IsHook() bool {
+	_, ok := m.Annotations()[HookAnnotation]
+	return ok
+}
+
+// KeepOnDelete is true if the manifest has the "helm.sh/resource-policy: keep" annotation
+func (m Manifest) KeepOnDelete() bool {
+	return strings.TrimSpace(m.Annotations()[ResourcePolicyAnnotation]) == ResourcePolicyKeep
+}
+
+// splitAnnotation splits a comma separated annotation value, items are lowercased as in Helm
+func splitAnnotation(value string) []string {
+	var res []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
+
+// Hooks returns hooks of the event sorted by weight and name, as Helm runs them
+func (l ManifestList) Hooks(event HookEvent) ManifestList {
+	type weighted struct {
+		m      Manifest
+		weight int
+	}
+
+	var hooks []weighted
+
+	for _, m := range l {
+		if hook, ok := m.Hook(); ok && hook.HasEvent(event) {
+			hooks = append(hooks, weighted{m: m, weight: hook.Weight})
+		}
+	}
+
+	sort.SliceStable(hooks, func(i, j int) bool {
+		if hooks[i].weight != hooks[j].weight {
+			return hooks[i].weight < hooks[j].weight
+		}
+
+		return hooks[i].m.Name() < hooks[j].m.Name()
+	})
+
+	res := make(ManifestList, 0, len(hooks))
+	for _, h := range hooks {
+		res = append(res, h.m)
+	}
+
+	return res
+}
+
+// Resources returns manifests that are not hooks in the list order
+func (l ManifestList) Resources() ManifestList {
+	return l.FilterFunc(func(m Manifest) bool {
+		return !m.IsHook()
+	})
+}
+
+// UnknownHooks returns hooks with unknown events in the list order, Helm skips them
+func (l ManifestList) UnknownHooks() ManifestList {
+	return l.FilterFunc(func(m Manifest) bool {
+		_, ok := m.Hook()
+		return m.IsHook() && !ok
+	})
+}
+
+// ReleaseOperation is a Helm release operation with pre- and post- hooks
+type ReleaseOperation string
+
+const (
+	OperationInstall  ReleaseOperation = "install"
+	OperationUpgrade  ReleaseOperation = "upgrade"
+	OperationRollback ReleaseOperation = "rollback"
+	OperationDelete   ReleaseOperation = "delete"
+)
+
+// HookPhases is a release split into phases of an operation
+type HookPhases struct {
+	// Pre are pre- hooks of the operation sorted by weight
+	Pre ManifestList
+	// Resources are manifests that are not hooks
+	Resources ManifestList
+	// Post are post- hooks of the operation sorted by weight
+	Post ManifestList
+	// Skipped are hooks with unknown events, see UnknownHooks
+	Skipped ManifestList
+}
+
+// Phases partitions the list into hooks of the operation and other resources,
+// hooks of other events, e.g. tests, are not included
+func (l ManifestList) Phases(op ReleaseOperation) HookPhases {
+	return HookPhases{
+		Pre:       l.Hooks(HookEvent("pre-" + op)),
+		Resources: l.Resources(),
+		Post:      l.Hooks(HookEvent("post-" + op)),
+		Skipped:   l.UnknownHooks(),
+	}
+}
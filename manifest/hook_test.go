@@ -0,0 +1,116 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const hooksYAML = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  annotations:
+    helm.sh/hook: Pre-Install, pre-upgrade
+    helm.sh/hook-weight: "5"
+    helm.sh/hook-delete-policy: hook-succeeded,Before-Hook-Creation
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: migrate-config
+  annotations:
+    helm.sh/hook: pre-install
+    helm.sh/hook-weight: "-1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  annotations:
+    helm.sh/resource-policy: keep
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: smoke
+  annotations:
+    helm.sh/hook: test-success
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: notify
+  annotations:
+    helm.sh/hook: post-install
+    helm.sh/hook-weight: invalid
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unknown
+  annotations:
+    helm.sh/hook: post-everything
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: partly-unknown
+  annotations:
+    helm.sh/hook: pre-install,post-everything
+`
+
+func Test_Hooks(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(hooksYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	list := ManifestList(manifests)
+
+	hook, ok := list[0].Hook()
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(hook).Should(Equal(&Hook{
+		Events:         []HookEvent{HookPreInstall, HookPreUpgrade},
+		Weight:         5,
+		DeletePolicies: []HookDeletePolicy{HookSucceeded, HookBeforeHookCreation},
+	}))
+
+	hook, _ = list[5].Hook()
+	g.Expect(hook.Weight).Should(Equal(0))
+	g.Expect(hook.DeletePolicies).Should(Equal([]HookDeletePolicy{HookBeforeHookCreation}))
+
+	hook, _ = list[4].Hook()
+	g.Expect(hook.Events).Should(Equal([]HookEvent{HookTest}))
+
+	_, ok = list[6].Hook()
+	g.Expect(ok).Should(BeFalse())
+	g.Expect(list[6].IsHook()).Should(BeTrue())
+	g.Expect(list[3].KeepOnDelete()).Should(BeTrue())
+	g.Expect(list[2].KeepOnDelete()).Should(BeFalse())
+
+	names := func(l ManifestList) []string {
+		var res []string
+		for _, m := range l {
+			res = append(res, m.Name())
+		}
+
+		return res
+	}
+
+	phases := list.Phases(OperationInstall)
+	g.Expect(names(phases.Pre)).Should(Equal([]string{"migrate-config", "migrate"}))
+	g.Expect(names(phases.Resources)).Should(Equal([]string{"web", "data"}))
+	g.Expect(names(phases.Post)).Should(Equal([]string{"notify"}))
+	g.Expect(names(phases.Skipped)).Should(Equal([]string{"unknown", "partly-unknown"}))
+
+	phases = list.Phases(OperationUpgrade)
+	g.Expect(names(phases.Pre)).Should(Equal([]string{"migrate"}))
+	g.Expect(phases.Post).Should(BeEmpty())
+}
@@ -0,0 +1,230 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitImage splits an image reference to the name, the tag and the digest,
+// e.g. "registry:5000/app:1.0@sha256:abc" is "registry:5000/app", "1.0" and "sha256:abc"
+func splitImage(image string) (name, tag, digest string) {
+	name = image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, digest = name[:i], name[i+1:]
+	}
+
+	// a colon after the last slash separates the tag, other colons are registry ports
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+
+	return name, tag, digest
+}
+
+// joinImage is the reverse of splitImage
+func joinImage(name, tag, digest string) string {
+	image := name
+	if tag != "" {
+		image += ":" + tag
+	}
+
+	if digest != "" {
+		image += "@" + digest
+	}
+
+	return image
+}
+
+// ImageRef is a container image found in a manifest
+type ImageRef struct {
+	Key ObjectKey
+	// Path is the path of the image field, e.g. "spec.template.spec.containers[0].image"
+	Path string
+	// Field is the list of the container: "initContainers", "containers" or "ephemeralContainers"
+	Field     string
+	Container string
+	Image     string
+}
+
+// ImageScanner finds images of Pods, pod templates of workloads and custom resources
+type ImageScanner struct {
+	// PodTemplatePaths are paths to pod templates of custom resources by "Kind.group",
+	// e.g. {"Rollout.argoproj.io": {"spec.template"}}. Built-in workloads are matched by group too,
+	// so a custom resource named like a built-in kind, e.g. "Deployment.example.com", needs paths here.
+	PodTemplatePaths map[string][]string
+}
+
+// templateAt is a pod template found in a manifest with its path
+type templateAt struct {
+	path string
+	obj  map[string]interface{}
+}
+
+func (s ImageScanner) templates(m Manifest) ([]templateAt, error) {
+	if path, ok := m.podTemplatePath(); ok {
+		obj, found := nestedMap(m, path...)
+		if !found {
+			return nil, nil
+		}
+
+		return []templateAt{{path: strings.Join(path, "."), obj: obj}}, nil
+	}
+
+	var res []templateAt
+
+	for _, path := range s.PodTemplatePaths[m.ObjectKey().GroupKind().String()] {
+		obj, found, err := m.GetMap(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.ObjectKey(), err)
+		}
+
+		if found {
+			res = append(res, templateAt{path: path, obj: obj})
+		}
+	}
+
+	return res, nil
+}
+
+// walk calls fn for every container with an image
+func (s ImageScanner) walk(l ManifestList, fn func(ref ImageRef, container map[string]interface{}) error) error {
+	for _, m := range l {
+		templates, err := s.templates(m)
+		if err != nil {
+			return err
+		}
+
+		for _, template := range templates {
+			podSpec, ok := nestedMap(template.obj, "spec")
+			if !ok {
+				continue
+			}
+
+			prefix := "spec"
+			if template.path != "" {
+				prefix = template.path + ".spec"
+			}
+
+			forEachContainer(podSpec, func(field string, i int, container map[string]interface{}) {
+				image := getFieldString(container, "image")
+				if image == "" || err != nil {
+					return
+				}
+
+				err = fn(ImageRef{
+					Key:       m.ObjectKey(),
+					Path:      fmt.Sprintf("%s.%s[%d].image", prefix, field, i),
+					Field:     field,
+					Container: getFieldString(container, "name"),
+					Image:     image,
+				}, container)
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Images returns images of containers in the list order
+func (s ImageScanner) Images(l ManifestList) ([]ImageRef, error) {
+	var refs []ImageRef
+
+	err := s.walk(l, func(ref ImageRef, _ map[string]interface{}) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// ImageRewriter returns the new image for the reference, ref.Image is returned to keep the image
+type ImageRewriter func(ref ImageRef) (string, error)
+
+// Rewrite replaces images of containers with results of the rewriter
+func (s ImageScanner) Rewrite(l ManifestList, rewrite ImageRewriter) error {
+	return s.walk(l, func(ref ImageRef, container map[string]interface{}) error {
+		image, err := rewrite(ref)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", ref.Key, ref.Path, err)
+		}
+
+		container["image"] = image
+
+		return nil
+	})
+}
+
+// Images returns images of Pods and workloads, see ImageScanner to find images of custom resources
+func (l ManifestList) Images() []ImageRef {
+	// the default scanner has no custom paths, so there are no errors
+	refs, _ := ImageScanner{}.Images(l)
+	return refs
+}
+
+const defaultRegistry = "docker.io"
+
+// imageRegistry splits the image name into the registry and the repository as the Docker CLI does,
+// e.g. "nginx" is "docker.io" and "library/nginx"
+func imageRegistry(name string) (registry, repository string) {
+	i := strings.Index(name, "/")
+	if i < 0 || !strings.ContainsAny(name[:i], ".:") && name[:i] != "localhost" {
+		if i < 0 {
+			name = "library/" + name
+		}
+
+		return defaultRegistry, name
+	}
+
+	return name[:i], name[i+1:]
+}
+
+// RegistryMapping moves images to other registries. Keys are registries or registries with
+// repository prefixes, e.g. "docker.io" or "quay.io/org", values are their replacements.
+// The longest matching key is used, images without a registry are in "docker.io".
+func RegistryMapping(mapping map[string]string) ImageRewriter {
+	return func(ref ImageRef) (string, error) {
+		name, tag, digest := splitImage(ref.Image)
+		registry, repository := imageRegistry(name)
+		full := registry + "/" + repository
+
+		match := ""
+
+		for from := range mapping {
+			if (full == from || strings.HasPrefix(full, from+"/")) && len(from) > len(match) {
+				match = from
+			}
+		}
+
+		if match == "" {
+			return ref.Image, nil
+		}
+
+		return joinImage(mapping[match]+strings.TrimPrefix(full, match), tag, digest), nil
+	}
+}
+
+// PinDigests adds digests to images, keys are images as they are written in manifests,
+// e.g. "nginx:1.25". Images with digests are kept, other images must have a digest in the map.
+func PinDigests(digests map[string]string) ImageRewriter {
+	return func(ref ImageRef) (string, error) {
+		name, tag, digest := splitImage(ref.Image)
+		if digest != "" {
+			return ref.Image, nil
+		}
+
+		digest, ok := digests[ref.Image]
+		if !ok {
+			return "", fmt.Errorf("no digest for image %s", ref.Image)
+		}
+
+		return joinImage(name, tag, digest), nil
+	}
+}
@@ -0,0 +1,133 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const imagesYAML = `
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: ops
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          initContainers:
+          - name: init
+            image: busybox
+          containers:
+          - name: backup
+            image: quay.io/org/backup:2.0
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: web
+  namespace: ops
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: registry.local:5000/web@sha256:abc
+`
+
+func Test_Images(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(imagesYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	list := ManifestList(manifests)
+
+	images := func(refs []ImageRef) []string {
+		var res []string
+		for _, ref := range refs {
+			res = append(res, ref.Key.Name+" "+ref.Path+" "+ref.Image)
+		}
+
+		return res
+	}
+
+	g.Expect(images(list.Images())).Should(Equal([]string{
+		"backup spec.jobTemplate.spec.template.spec.initContainers[0].image busybox",
+		"backup spec.jobTemplate.spec.template.spec.containers[0].image quay.io/org/backup:2.0",
+	}))
+
+	scanner := ImageScanner{PodTemplatePaths: map[string][]string{"Rollout.argoproj.io": {"spec.template"}}}
+
+	refs, err := scanner.Images(list)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(refs).Should(HaveLen(3))
+	g.Expect(refs[2].Container).Should(Equal("web"))
+	g.Expect(refs[2].Field).Should(Equal("containers"))
+
+	err = scanner.Rewrite(list, RegistryMapping(map[string]string{
+		"docker.io":            "mirror.local/hub",
+		"quay.io/org":          "mirror.local/org",
+		"registry.local:50001": "wrong",
+	}))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	err = scanner.Rewrite(list, PinDigests(map[string]string{"mirror.local/hub/library/busybox": "sha256:111"}))
+	g.Expect(err).Should(MatchError("CronJob.batch/ops/backup: spec.jobTemplate.spec.template.spec.containers[0].image: no digest for image mirror.local/org/backup:2.0"))
+
+	err = scanner.Rewrite(list, PinDigests(map[string]string{
+		"mirror.local/hub/library/busybox": "sha256:111",
+		"mirror.local/org/backup:2.0":      "sha256:222",
+	}))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	refs, err = scanner.Images(list)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect([]string{refs[0].Image, refs[1].Image, refs[2].Image}).Should(Equal([]string{
+		"mirror.local/hub/library/busybox@sha256:111",
+		"mirror.local/org/backup:2.0@sha256:222",
+		"registry.local:5000/web@sha256:abc",
+	}))
+}
+
+func Test_SplitImage(t *testing.T) {
+	g := NewWithT(t)
+
+	name, tag, digest := splitImage("localhost:5000/app:1.0@sha256:abc")
+	g.Expect([]string{name, tag, digest}).Should(Equal([]string{"localhost:5000/app", "1.0", "sha256:abc"}))
+
+	name, tag, digest = splitImage("localhost:5000/app")
+	g.Expect([]string{name, tag, digest}).Should(Equal([]string{"localhost:5000/app", "", ""}))
+}
+
+func Test_ImageScanner_CustomKindsNamedLikeBuiltins(t *testing.T) {
+	g := NewWithT(t)
+
+	m, err := NewFromYAML(`
+apiVersion: example.com/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: wrong
+        image: wrong:1
+  pod:
+    spec:
+      containers:
+      - name: web
+        image: web:1
+`)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(ManifestList{m}.Images()).Should(BeEmpty())
+
+	refs, err := ImageScanner{PodTemplatePaths: map[string][]string{"Deployment.example.com": {"spec.pod"}}}.Images(ManifestList{m})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(refs).Should(HaveLen(1))
+	g.Expect(refs[0].Path).Should(Equal("spec.pod.spec.containers[0].image"))
+}
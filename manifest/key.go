@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ObjectKey identifies an object in a cluster. Namespace is empty for cluster-scoped objects.
+// Version is kept to address the object, but it is not a part of the identity:
+// the same object is served by all versions of a group, see SameObject.
+type ObjectKey struct {
+	schema.GroupVersionKind
+	Namespace string
+	Name      string
+}
+
+// String returns the key in the "Kind.group/namespace/name" form, e.g. "Deployment.apps/default/app",
+// namespace is omitted for cluster-scoped objects
+func (k ObjectKey) String() string {
+	gk := k.GroupKind().String()
+	if k.Namespace == "" {
+		return gk + "/" + k.Name
+	}
+
+	return gk + "/" + k.Namespace + "/" + k.Name
+}
+
+// SameObject is true if keys point to the same object, versions are not compared
+func (k ObjectKey) SameObject(other ObjectKey) bool {
+	return k.GroupKind() == other.GroupKind() && k.Namespace == other.Namespace && k.Name == other.Name
+}
+
+// ParseObjectKey parses the String form of the key, the version is not set
+func ParseObjectKey(s string) (ObjectKey, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return ObjectKey{}, fmt.Errorf("object key %q: expected Kind.group/[namespace/]name", s)
+	}
+
+	gk := schema.ParseGroupKind(parts[0])
+
+	key := ObjectKey{GroupVersionKind: gk.WithVersion(""), Name: parts[len(parts)-1]}
+	if len(parts) == 3 {
+		key.Namespace = parts[1]
+	}
+
+	if key.Kind == "" || key.Name == "" {
+		return ObjectKey{}, fmt.Errorf("object key %q: expected Kind.group/[namespace/]name", s)
+	}
+
+	return key, nil
+}
+
+// ObjectKey returns the key of the manifest. metadata.namespace is used as is,
+// unlike Id there is no "default" namespace, use DefaultNamespace to set it according to the scope.
+func (m Manifest) ObjectKey() ObjectKey {
+	return ObjectKey{
+		GroupVersionKind: schema.FromAPIVersionAndKind(m.ApiVersion(), m.Kind()),
+		Namespace:        m.Unstructured().GetNamespace(),
+		Name:             m.Name(),
+	}
+}
+
+// ScopeResolver returns the API resource of a kind. *client.Client satisfies it
+// with the cluster discovery and NewStaticResolver makes one from resource tables.
+type ScopeResolver interface {
+	APIResource(apiVersion, kind string) (*metav1.APIResource, error)
+}
+
+type staticResolver []*metav1.APIResourceList
+
+// NewStaticResolver returns a resolver for the resource lists, e.g. fake.ClusterResources(ver)
+func NewStaticResolver(resources []*metav1.APIResourceList) ScopeResolver {
+	return staticResolver(resources)
+}
+
+func (r staticResolver) APIResource(apiVersion, kind string) (*metav1.APIResource, error) {
+	for _, list := range r {
+		if list.GroupVersion != apiVersion {
+			continue
+		}
+
+		for i := range list.APIResources {
+			res := list.APIResources[i]
+			// skip subresources, e.g. deployments/scale has kind Scale
+			if res.Kind == kind && !strings.Contains(res.Name, "/") {
+				return &res, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("apiVersion '%s', kind '%s' is not supported by cluster", apiVersion, kind)
+}
+
+// IsNamespaced returns true if the kind of the manifest is namespaced
+func IsNamespaced(m Manifest, resolver ScopeResolver) (bool, error) {
+	res, err := resolver.APIResource(m.ApiVersion(), m.Kind())
+	if err != nil {
+		return false, err
+	}
+
+	return res.Namespaced, nil
+}
+
+// DefaultNamespace sets metadata.namespace of a namespaced manifest to ns if it is not set
+// and removes metadata.namespace of a cluster-scoped manifest.
+func DefaultNamespace(m Manifest, ns string, resolver ScopeResolver) error {
+	namespaced, err := IsNamespaced(m, resolver)
+	if err != nil {
+		return fmt.Errorf("%s: %w", m.Id(), err)
+	}
+
+	switch {
+	case !namespaced:
+		if metadata, ok := m["metadata"].(map[string]interface{}); ok {
+			delete(metadata, "namespace")
+		}
+	case m.Unstructured().GetNamespace() == "":
+		m.SetNamespace(ns)
+	}
+
+	return nil
+}
+
+// DefaultNamespaces calls DefaultNamespace for every manifest
+func DefaultNamespaces(manifests []Manifest, ns string, resolver ScopeResolver) error {
+	for _, m := range manifests {
+		err := DefaultNamespace(m, ns, resolver)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var testResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			{Name: "secrets", Kind: "Secret", Namespaced: true},
+			{Name: "services", Kind: "Service", Namespaced: true},
+			{Name: "serviceaccounts", Kind: "ServiceAccount", Namespaced: true},
+			{Name: "namespaces", Kind: "Namespace"},
+		},
+	},
+	{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+			{Name: "deployments/scale", Kind: "Scale", Group: "autoscaling", Version: "v1", Namespaced: true},
+		},
+	},
+}
+
+var rbacResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "rbac.authorization.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "rolebindings", Kind: "RoleBinding", Namespaced: true},
+			{Name: "clusterrolebindings", Kind: "ClusterRoleBinding"},
+			{Name: "roles", Kind: "Role", Namespaced: true},
+			{Name: "clusterroles", Kind: "ClusterRole"},
+		},
+	},
+}
+
+var networkingResources = []*metav1.APIResourceList{
+	{
+		GroupVersion: "networking.k8s.io/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "ingresses", Kind: "Ingress", Namespaced: true},
+		},
+	},
+}
+
+func Test_ObjectKey(t *testing.T) {
+	g := NewWithT(t)
+
+	m := New("apps/v1", "Deployment", "app")
+	m.SetNamespace("web")
+
+	key := m.ObjectKey()
+	g.Expect(key.String()).Should(Equal("Deployment.apps/web/app"))
+	g.Expect(key.Version).Should(Equal("v1"))
+
+	parsed, err := ParseObjectKey("Deployment.apps/web/app")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(parsed.SameObject(key)).Should(BeTrue())
+	g.Expect(parsed == key).Should(BeFalse())
+
+	g.Expect(New("v1", "Namespace", "web").ObjectKey().String()).Should(Equal("Namespace/web"))
+
+	_, err = ParseObjectKey("web")
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func Test_DefaultNamespace(t *testing.T) {
+	g := NewWithT(t)
+	resolver := NewStaticResolver(testResources)
+
+	cm := New("v1", "ConfigMap", "cm")
+	deploy := New("apps/v1", "Deployment", "app")
+	deploy.SetNamespace("other")
+	ns := New("v1", "Namespace", "web")
+	ns.SetNamespace("web")
+
+	g.Expect(DefaultNamespaces([]Manifest{cm, deploy, ns}, "web", resolver)).Should(Succeed())
+	g.Expect(cm.ObjectKey().Namespace).Should(Equal("web"))
+	g.Expect(deploy.ObjectKey().Namespace).Should(Equal("other"))
+	g.Expect(ns.Metadata()).ShouldNot(HaveKey("namespace"))
+
+	err := DefaultNamespace(New("apps/v1", "Scale", "app"), "web", resolver)
+	g.Expect(err).Should(MatchError(ContainSubstring("kind 'Scale' is not supported")))
+}
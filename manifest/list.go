@@ -0,0 +1,261 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ManifestList is a set of manifests, e.g. a rendered chart
+type ManifestList []Manifest
+
+// Selector matches manifests, empty fields match any value
+type Selector struct {
+	Group   string
+	Version string
+	Kind    string
+	// Namespace is compared with metadata.namespace as is, see DefaultNamespaces
+	Namespace string
+	Labels    labels.Selector
+}
+
+// Matches is true if the manifest matches all non-empty fields of the selector
+func (s Selector) Matches(m Manifest) bool {
+	key := m.ObjectKey()
+
+	switch {
+	case s.Group != "" && s.Group != key.Group:
+		return false
+	case s.Version != "" && s.Version != key.Version:
+		return false
+	case s.Kind != "" && s.Kind != key.Kind:
+		return false
+	case s.Namespace != "" && s.Namespace != key.Namespace:
+		return false
+	case s.Labels != nil && !s.Labels.Matches(labels.Set(m.Labels())):
+		return false
+	}
+
+	return true
+}
+
+// Filter returns manifests matching the selector
+func (l ManifestList) Filter(s Selector) ManifestList {
+	return l.FilterFunc(s.Matches)
+}
+
+// FilterFunc returns manifests for which fn is true
+func (l ManifestList) FilterFunc(fn func(m Manifest) bool) ManifestList {
+	var res ManifestList
+
+	for _, m := range l {
+		if fn(m) {
+			res = append(res, m)
+		}
+	}
+
+	return res
+}
+
+// CRDs returns CustomResourceDefinition manifests
+func (l ManifestList) CRDs() ManifestList {
+	return l.Filter(Selector{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"})
+}
+
+// Find returns the manifest of the same object as the key, versions are not compared
+func (l ManifestList) Find(key ObjectKey) (Manifest, bool) {
+	for _, m := range l {
+		if m.ObjectKey().SameObject(key) {
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+// Keys returns keys of manifests in the list order
+func (l ManifestList) Keys() []ObjectKey {
+	keys := make([]ObjectKey, 0, len(l))
+	for _, m := range l {
+		keys = append(keys, m.ObjectKey())
+	}
+
+	return keys
+}
+
+// DuplicateError is returned for manifests of the same object
+type DuplicateError struct {
+	Key ObjectKey
+	// Items are indexes of the duplicates in the list
+	Items []int
+}
+
+func (e *DuplicateError) Error() string {
+	items := make([]string, 0, len(e.Items))
+	for _, i := range e.Items {
+		items = append(items, fmt.Sprint(i))
+	}
+
+	return fmt.Sprintf("duplicate manifest %s: items %s", e.Key, strings.Join(items, ", "))
+}
+
+// Duplicates returns manifests of the same object, keys are compared without versions.
+// Errors are in the order of the first occurrence.
+func (l ManifestList) Duplicates() []*DuplicateError {
+	var order []schemaKey
+
+	seen := make(map[schemaKey]*DuplicateError)
+
+	for i, m := range l {
+		key := m.ObjectKey()
+		id := schemaKey{key.GroupKind(), key.Namespace, key.Name}
+
+		dup, ok := seen[id]
+		if !ok {
+			seen[id] = &DuplicateError{Key: key, Items: []int{i}}
+			order = append(order, id)
+
+			continue
+		}
+
+		dup.Items = append(dup.Items, i)
+	}
+
+	var res []*DuplicateError
+
+	for _, id := range order {
+		if len(seen[id].Items) > 1 {
+			res = append(res, seen[id])
+		}
+	}
+
+	return res
+}
+
+// CheckDuplicates returns an error joining DuplicateError for every duplicated object
+func (l ManifestList) CheckDuplicates() error {
+	var errs []error
+	for _, dup := range l.Duplicates() {
+		errs = append(errs, dup)
+	}
+
+	return errors.Join(errs...)
+}
+
+// schemaKey is the identity of an object without a version
+type schemaKey struct {
+	gk        schema.GroupKind
+	namespace string
+	name      string
+}
+
+// GroupBy splits the list by the key returned by fn, the order of manifests is kept in groups
+func (l ManifestList) GroupBy(fn func(m Manifest) string) map[string]ManifestList {
+	res := make(map[string]ManifestList)
+	for _, m := range l {
+		k := fn(m)
+		res[k] = append(res[k], m)
+	}
+
+	return res
+}
+
+// ByNamespace groups manifests by metadata.namespace, cluster-scoped manifests are under ""
+func (l ManifestList) ByNamespace() map[string]ManifestList {
+	return l.GroupBy(func(m Manifest) string {
+		return m.ObjectKey().Namespace
+	})
+}
+
+// ByKind groups manifests by kind and group in the "Kind.group" form
+func (l ManifestList) ByKind() map[string]ManifestList {
+	return l.GroupBy(func(m Manifest) string {
+		return m.ObjectKey().GroupKind().String()
+	})
+}
+
+// Sort sorts the list in place by group, kind, namespace, name and version.
+// The sort is stable, so duplicates keep their order.
+func (l ManifestList) Sort() {
+	l.SortFunc(func(a, b Manifest) bool {
+		ka, kb := a.ObjectKey(), b.ObjectKey()
+
+		for _, pair := range [][2]string{
+			{ka.Group, kb.Group},
+			{ka.Kind, kb.Kind},
+			{ka.Namespace, kb.Namespace},
+			{ka.Name, kb.Name},
+			{ka.Version, kb.Version},
+		} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+
+		return false
+	})
+}
+
+// SortFunc sorts the list in place with the less function, the sort is stable
+func (l ManifestList) SortFunc(less func(a, b Manifest) bool) {
+	sort.SliceStable(l, func(i, j int) bool {
+		return less(l[i], l[j])
+	})
+}
+
+// DeepCopy returns a copy of the list with copies of manifests
+func (l ManifestList) DeepCopy() ManifestList {
+	if l == nil {
+		return nil
+	}
+
+	res := make(ManifestList, 0, len(l))
+	for _, m := range l {
+		res = append(res, m.DeepCopy())
+	}
+
+	return res
+}
+
+// DeepCopy returns a copy of the manifest that shares no maps and slices with it
+func (m Manifest) DeepCopy() Manifest {
+	if m == nil {
+		return nil
+	}
+
+	return deepCopyValue(map[string]interface{}(m)).(map[string]interface{})
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			res[k] = deepCopyValue(item)
+		}
+
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = deepCopyValue(item)
+		}
+
+		return res
+	case map[string]string:
+		res := make(map[string]string, len(v))
+		for k, item := range v {
+			res[k] = item
+		}
+
+		return res
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
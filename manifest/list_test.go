@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const listYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: db
+  namespace: dev
+---
+apiVersion: extensions/v1beta1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+`
+
+func Test_ManifestList(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(listYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	list := ManifestList(manifests)
+
+	selector, err := labels.Parse("app=web")
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	keys := func(l ManifestList) []string {
+		var res []string
+		for _, key := range l.Keys() {
+			res = append(res, key.String())
+		}
+
+		return res
+	}
+
+	g.Expect(keys(list.Filter(Selector{Labels: selector}))).Should(Equal([]string{"Deployment.apps/prod/web", "ConfigMap/prod/web"}))
+	g.Expect(keys(list.Filter(Selector{Kind: "ConfigMap", Namespace: "dev"}))).Should(Equal([]string{"ConfigMap/dev/db"}))
+	g.Expect(keys(list.CRDs())).Should(Equal([]string{"CustomResourceDefinition.apiextensions.k8s.io/widgets.example.com"}))
+
+	g.Expect(list.ByNamespace()).Should(HaveLen(3))
+	g.Expect(list.ByKind()["ConfigMap"]).Should(HaveLen(2))
+
+	g.Expect(list.Duplicates()).Should(BeEmpty())
+
+	list = append(list, list[0])
+	err = list.CheckDuplicates()
+	g.Expect(err).Should(MatchError("duplicate manifest Deployment.apps/prod/web: items 0, 5"))
+
+	var dupErr *DuplicateError
+	g.Expect(errors.As(err, &dupErr)).Should(BeTrue())
+
+	sorted := list.DeepCopy()
+	sorted.Sort()
+	g.Expect(keys(sorted)).Should(Equal([]string{
+		"ConfigMap/dev/db",
+		"ConfigMap/prod/web",
+		"CustomResourceDefinition.apiextensions.k8s.io/widgets.example.com",
+		"Deployment.apps/prod/web",
+		"Deployment.apps/prod/web",
+		"Deployment.extensions/prod/web",
+	}))
+
+	sorted[0].SetLabel("copied", "true")
+	g.Expect(list[3].Labels()).ShouldNot(HaveKey("copied"))
+}
@@ -1,7 +1,10 @@
 package manifest
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/yaml"
@@ -29,10 +32,25 @@ func New(apiVersion, kind, name string) Manifest {
 	})
 }
 
+// NewFromYAML returns the manifest of the first document, errors are *ParseError with the position
+// of the document and the "# Source:" template of Helm rendered output
 func NewFromYAML(yamlOrJson string) (Manifest, error) {
+	doc, err := NewDecoder(strings.NewReader(yamlOrJson)).Next()
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return nil, nil
+	case err != nil:
+		return Manifest{}, err
+	}
+
+	return doc.Manifest, nil
+}
+
+func unmarshalManifest(raw []byte) (Manifest, error) {
 	var m Manifest
 
-	err := yaml.Unmarshal([]byte(yamlOrJson), &m)
+	err := yaml.Unmarshal(raw, &m)
 	if err != nil {
 		return Manifest{}, err
 	}
@@ -1,20 +1,142 @@
 package manifest
 
-import "github.com/flant/kube-client/manifest/releaseutil"
+import (
+	"strings"
+)
 
+// ListFromYamlDocs returns manifests with kind, apiVersion and metadata.name, other documents are skipped.
+// Use ListFromYamlDocsWithOptions to get positions of manifests in the stream.
 func ListFromYamlDocs(rawManifests string) ([]Manifest, error) {
+	docs, _, err := ListFromYamlDocsWithOptions(rawManifests, ParseOptions{})
+	if err != nil {
+		return nil, err
+	}
+
 	var manifests []Manifest
+	for _, doc := range docs {
+		manifests = append(manifests, doc.Manifest)
+	}
+
+	return manifests, nil
+}
+
+// ParseOptions changes which documents ListFromYamlDocsWithOptions accepts
+type ParseOptions struct {
+	// ExpandLists returns items of "kind: List" and other *List kinds instead of the list
+	ExpandLists bool
+	// AllowGenerateName accepts manifests with metadata.generateName instead of metadata.name
+	AllowGenerateName bool
+}
+
+// SkipReason describes why a document is not returned as a manifest
+type SkipReason string
+
+const (
+	SkipEmpty             SkipReason = "empty"
+	SkipCommentOnly       SkipReason = "comment-only"
+	SkipNotObject         SkipReason = "not an object"
+	SkipMissingAPIVersion SkipReason = "missing apiVersion"
+	SkipMissingKind       SkipReason = "missing kind"
+	SkipMissingName       SkipReason = "missing name"
+)
 
-	for _, doc := range releaseutil.SplitManifests(rawManifests) {
-		m, err := NewFromYAML(doc)
-		if err != nil {
-			return nil, err
+// SkippedDocument is a document or a list item that is not returned as a manifest
+type SkippedDocument struct {
+	Source Source
+	// Item is the index of a skipped item of a List, it is -1 for documents
+	Item   int
+	Reason SkipReason
+}
+
+// ParseReport lists documents skipped by ListFromYamlDocsWithOptions
+type ParseReport struct {
+	Skipped []SkippedDocument
+}
+
+// ListFromYamlDocsWithOptions returns documents with manifests from a multi-document YAML
+// and reports documents that are skipped with reasons. Items of an expanded List are returned
+// as documents with the position of the List and the raw content of the List.
+func ListFromYamlDocsWithOptions(rawManifests string, opts ParseOptions) ([]*Document, *ParseReport, error) {
+	docs, err := ReadAll(strings.NewReader(rawManifests))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res []*Document
+
+	report := &ParseReport{}
+
+	for _, doc := range docs {
+		skip := func(item int, reason SkipReason) {
+			report.Skipped = append(report.Skipped, SkippedDocument{Source: doc.Source, Item: item, Reason: reason})
 		}
 
-		if m.HasBasicFields() {
-			manifests = append(manifests, m)
+		if doc.Manifest == nil {
+			if len(strings.TrimSpace(string(doc.Raw))) == 0 {
+				skip(-1, SkipEmpty)
+			} else {
+				skip(-1, SkipCommentOnly)
+			}
+
+			continue
+		}
+
+		items, isList := doc.Manifest.listItems()
+		if !opts.ExpandLists || !isList {
+			if reason := opts.check(doc.Manifest); reason != "" {
+				skip(-1, reason)
+				continue
+			}
+
+			res = append(res, doc)
+
+			continue
+		}
+
+		for i, item := range items {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				skip(i, SkipNotObject)
+				continue
+			}
+
+			if reason := opts.check(obj); reason != "" {
+				skip(i, reason)
+				continue
+			}
+
+			res = append(res, &Document{Manifest: obj, Source: doc.Source, Raw: doc.Raw})
 		}
 	}
 
-	return manifests, nil
+	return res, report, nil
+}
+
+// check returns the reason to skip the manifest, it is empty for valid manifests
+func (opts ParseOptions) check(m Manifest) SkipReason {
+	switch {
+	case m.ApiVersion() == "":
+		return SkipMissingAPIVersion
+	case m.Kind() == "":
+		return SkipMissingKind
+	case m.Name() == "" && (!opts.AllowGenerateName || m.GenerateName() == ""):
+		return SkipMissingName
+	}
+
+	return ""
+}
+
+// listItems returns items of "kind: List" and other *List kinds with an items list.
+// Other *List kinds, e.g. a custom resource "kind: AllowList" with a spec, are not lists.
+func (m Manifest) listItems() ([]interface{}, bool) {
+	items, ok := m["items"].([]interface{})
+
+	switch {
+	case m.Kind() == "List":
+		return items, ok || m["items"] == nil
+	case strings.HasSuffix(m.Kind(), "List"):
+		return items, ok
+	}
+
+	return nil, false
 }
@@ -103,3 +103,101 @@ func Test_RenderedChart_To_ManifestList(t *testing.T) {
 	g.Expect(err).ShouldNot(HaveOccurred())
 	g.Expect(manifests).To(HaveLen(3), "GetManifestListFromYamlDocuments should return only templates with all basic fields: kind, apiVersion and metadata.name")
 }
+
+const listOutput = `
+---
+# Source: chart/templates/empty.yaml
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: first
+- apiVersion: v1
+  kind: ConfigMap
+  metadata: {}
+- just a string
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  generateName: migrate-
+---
+kind: Secret
+metadata:
+  name: no-version
+---
+`
+
+func Test_ListFromYamlDocsWithOptions(t *testing.T) {
+	g := NewWithT(t)
+
+	docs, report, err := ListFromYamlDocsWithOptions(listOutput, ParseOptions{})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(docs).Should(BeEmpty())
+	g.Expect(report.Skipped).Should(HaveLen(5))
+
+	docs, report, err = ListFromYamlDocsWithOptions(listOutput, ParseOptions{ExpandLists: true, AllowGenerateName: true})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	var ids []string
+	for _, doc := range docs {
+		ids = append(ids, doc.Manifest.Kind()+"/"+doc.Manifest.Name()+doc.Manifest.GenerateName())
+	}
+
+	g.Expect(ids).Should(Equal([]string{"ConfigMap/first", "Job/migrate-"}))
+	g.Expect(docs[0].Source.Index).Should(Equal(1))
+	g.Expect(docs[1].Source.Index).Should(Equal(2))
+	g.Expect(docs[1].Source.StartLine).Should(Equal(17))
+
+	type skipped struct {
+		Index  int
+		Item   int
+		Reason SkipReason
+	}
+
+	var reasons []skipped
+	for _, s := range report.Skipped {
+		reasons = append(reasons, skipped{s.Source.Index, s.Item, s.Reason})
+	}
+
+	g.Expect(reasons).Should(Equal([]skipped{
+		{0, -1, SkipCommentOnly},
+		{1, 1, SkipMissingName},
+		{1, 2, SkipNotObject},
+		{3, -1, SkipMissingAPIVersion},
+		{4, -1, SkipEmpty},
+	}))
+}
+
+func Test_ListFromYamlDocsWithOptions_ListKinds(t *testing.T) {
+	g := NewWithT(t)
+
+	docs, report, err := ListFromYamlDocsWithOptions(`
+apiVersion: example.com/v1
+kind: AllowList
+metadata:
+  name: ips
+spec:
+  cidrs:
+  - 10.0.0.0/8
+---
+apiVersion: v1
+kind: ConfigMapList
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: first
+---
+apiVersion: v1
+kind: List
+`, ParseOptions{ExpandLists: true})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(report.Skipped).Should(BeEmpty())
+	g.Expect(docs).Should(HaveLen(2))
+	g.Expect(docs[0].Manifest.Kind()).Should(Equal("AllowList"))
+	g.Expect(docs[1].Manifest.Name()).Should(Equal("first"))
+}
@@ -0,0 +1,215 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// CreatePatch returns a patch of the type that changes the original manifest to the modified one.
+// Strategic merge patches are supported for kinds registered in the converter scheme only,
+// use a merge patch for custom resources as kubectl does.
+func (c *Converter) CreatePatch(original, modified Manifest, pt types.PatchType) ([]byte, error) {
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+
+	modifiedJSON, err := json.Marshal(modified)
+	if err != nil {
+		return nil, err
+	}
+
+	switch pt {
+	case types.MergePatchType:
+		return jsonpatch.CreateMergePatch(originalJSON, modifiedJSON)
+	case types.JSONPatchType:
+		return createJSONPatch(originalJSON, modifiedJSON)
+	case types.StrategicMergePatchType:
+		obj, err := c.patchSchema(modified)
+		if err != nil {
+			return nil, err
+		}
+
+		return strategicpatch.CreateTwoWayMergePatch(originalJSON, modifiedJSON, obj)
+	default:
+		return nil, fmt.Errorf("patch type %q is not supported", pt)
+	}
+}
+
+// ApplyPatch returns a copy of the manifest with the patch of the type applied, see CreatePatch
+func (c *Converter) ApplyPatch(m Manifest, patch []byte, pt types.PatchType) (Manifest, error) {
+	original, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+
+	switch pt {
+	case types.MergePatchType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case types.JSONPatchType:
+		var ops jsonpatch.Patch
+
+		ops, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			patched, err = ops.Apply(original)
+		}
+	case types.StrategicMergePatchType:
+		var obj interface{}
+
+		obj, err = c.patchSchema(m)
+		if err == nil {
+			patched, err = strategicpatch.StrategicMergePatch(original, patch, obj)
+		}
+	default:
+		return nil, fmt.Errorf("patch type %q is not supported", pt)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: applying %s: %w", m.Id(), pt, err)
+	}
+
+	var res Manifest
+
+	err = json.Unmarshal(patched, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// patchSchema returns a typed object used by strategicpatch to look up patch strategies and merge keys
+func (c *Converter) patchSchema(m Manifest) (interface{}, error) {
+	gvk := schema.FromAPIVersionAndKind(m.ApiVersion(), m.Kind())
+
+	obj, err := c.scheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("%s: strategic merge patch needs a registered type: %w", m.Id(), err)
+	}
+
+	return obj, nil
+}
+
+// CreatePatch returns a patch between manifests using client-go types, see Converter.CreatePatch
+func CreatePatch(original, modified Manifest, pt types.PatchType) ([]byte, error) {
+	return defaultConverter.CreatePatch(original, modified, pt)
+}
+
+// ApplyPatch applies the patch using client-go types, see Converter.ApplyPatch
+func ApplyPatch(m Manifest, patch []byte, pt types.PatchType) (Manifest, error) {
+	return defaultConverter.ApplyPatch(m, patch, pt)
+}
+
+// jsonPatchOp is an operation of RFC 6902 JSON patch
+type jsonPatchOp struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON omits the value of remove operations only, null is a valid value to add
+func (op jsonPatchOp) MarshalJSON() ([]byte, error) {
+	if op.Op == "remove" {
+		return json.Marshal(map[string]interface{}{"op": op.Op, "path": op.Path})
+	}
+
+	return json.Marshal(map[string]interface{}{"op": op.Op, "path": op.Path, "value": op.Value})
+}
+
+// createJSONPatch returns RFC 6902 operations changing the original document to the modified one.
+// Maps are compared by keys, lists are compared by indexes.
+func createJSONPatch(originalJSON, modifiedJSON []byte) ([]byte, error) {
+	var original, modified interface{}
+
+	err := json.Unmarshal(originalJSON, &original)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(modifiedJSON, &modified)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := diffJSON("", original, modified, []jsonPatchOp{})
+
+	return json.Marshal(ops)
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func diffJSON(path string, a, b interface{}, ops []jsonPatchOp) []jsonPatchOp {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		for _, k := range sortedKeys(a) {
+			p := path + "/" + jsonPointerEscaper.Replace(k)
+			if bv, found := b[k]; found {
+				ops = diffJSON(p, a[k], bv, ops)
+			} else {
+				ops = append(ops, jsonPatchOp{Op: "remove", Path: p})
+			}
+		}
+
+		for _, k := range sortedKeys(b) {
+			if _, found := a[k]; !found {
+				ops = append(ops, jsonPatchOp{Op: "add", Path: path + "/" + jsonPointerEscaper.Replace(k), Value: b[k]})
+			}
+		}
+
+		return ops
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+
+		common := min(len(a), len(b))
+		for i := 0; i < common; i++ {
+			ops = diffJSON(path+"/"+strconv.Itoa(i), a[i], b[i], ops)
+		}
+
+		// remove from the end, so indexes of the remaining items are not shifted
+		for i := len(a) - 1; i >= common; i-- {
+			ops = append(ops, jsonPatchOp{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+
+		for i := common; i < len(b); i++ {
+			ops = append(ops, jsonPatchOp{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: b[i]})
+		}
+
+		return ops
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return ops
+	}
+
+	return append(ops, jsonPatchOp{Op: "replace", Path: path, Value: b})
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
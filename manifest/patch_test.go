@@ -0,0 +1,84 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const patchOriginal = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+    tier/name: front
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1
+      - name: sidecar
+        image: sidecar:1
+`
+
+const patchModified = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+    team: core
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:2
+`
+
+func Test_CreatePatch(t *testing.T) {
+	original, modified := MustFromYAML(patchOriginal), MustFromYAML(patchModified)
+
+	expected := map[types.PatchType]string{
+		types.MergePatchType: `{"metadata":{"labels":{"team":"core","tier/name":null}},"spec":{"replicas":2,"template":{"spec":{"containers":[{"image":"app:2","name":"app"}]}}}}`,
+		types.JSONPatchType: `[{"op":"remove","path":"/metadata/labels/tier~1name"},{"op":"add","path":"/metadata/labels/team","value":"core"},` +
+			`{"op":"replace","path":"/spec/replicas","value":2},{"op":"replace","path":"/spec/template/spec/containers/0/image","value":"app:2"},` +
+			`{"op":"remove","path":"/spec/template/spec/containers/1"}]`,
+		types.StrategicMergePatchType: `{"metadata":{"labels":{"team":"core","tier/name":null}},"spec":{"replicas":2,"template":{"spec":{"$setElementOrder/containers":[{"name":"app"}],` +
+			`"containers":[{"image":"app:2","name":"app"},{"$patch":"delete","name":"sidecar"}]}}}}`,
+	}
+
+	for pt, patch := range expected {
+		t.Run(string(pt), func(t *testing.T) {
+			g := NewWithT(t)
+
+			created, err := CreatePatch(original, modified, pt)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(string(created)).Should(MatchJSON(patch))
+
+			patched, err := ApplyPatch(original, created, pt)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(patched).Should(Equal(modified))
+		})
+	}
+}
+
+func Test_CreatePatch_CustomResource(t *testing.T) {
+	g := NewWithT(t)
+
+	widget := New("example.com/v1", "Widget", "w")
+
+	_, err := CreatePatch(widget, widget, types.StrategicMergePatchType)
+	g.Expect(err).Should(MatchError(ContainSubstring("strategic merge patch needs a registered type")))
+
+	patch, err := CreatePatch(widget, widget, types.MergePatchType)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(patch)).Should(Equal("{}"))
+}
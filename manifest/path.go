@@ -0,0 +1,407 @@
+package manifest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Paths address nested fields of a manifest. Keys are separated by dots, list items
+// are addressed by index and keys with dots or brackets are quoted in brackets:
+//
+//	spec.template.spec.containers[0].image
+//	.metadata.annotations["app.kubernetes.io/name"]
+//	$.spec.selector.matchLabels['app']
+//
+// The leading "$" and "." are optional.
+
+type pathElement struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func parsePath(path string) ([]pathElement, error) {
+	p := strings.TrimPrefix(path, "$")
+	if p == "" {
+		return nil, fmt.Errorf("path %q is empty", path)
+	}
+
+	var elems []pathElement
+
+	for i := 0; i < len(p); {
+		switch {
+		case p[i] == '[':
+			if i+1 < len(p) && (p[i+1] == '"' || p[i+1] == '\'') {
+				quote := p[i+1]
+
+				closing := strings.IndexByte(p[i+2:], quote)
+				if closing < 0 || i+2+closing+1 >= len(p) || p[i+2+closing+1] != ']' {
+					return nil, fmt.Errorf("path %q: unclosed quote at %d", path, i+1)
+				}
+
+				elems = append(elems, pathElement{key: p[i+2 : i+2+closing]})
+				i += 2 + closing + 2
+
+				continue
+			}
+
+			end := strings.IndexByte(p[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("path %q: unclosed bracket at %d", path, i)
+			}
+
+			inner := p[i+1 : i+end]
+
+			index, err := strconv.Atoi(inner)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("path %q: invalid index %q", path, inner)
+			}
+
+			elems = append(elems, pathElement{index: index, isIndex: true})
+			i += end + 1
+		default:
+			if p[i] == '.' {
+				i++
+			} else if i > 0 {
+				return nil, fmt.Errorf("path %q: unexpected %q at %d", path, p[i], i)
+			}
+
+			end := strings.IndexAny(p[i:], ".[")
+			if end < 0 {
+				end = len(p) - i
+			}
+
+			if end == 0 {
+				return nil, fmt.Errorf("path %q: empty key at %d", path, i)
+			}
+
+			elems = append(elems, pathElement{key: p[i : i+end]})
+			i += end
+		}
+	}
+
+	return elems, nil
+}
+
+func formatPath(elems []pathElement) string {
+	if len(elems) == 0 {
+		return "."
+	}
+
+	var sb strings.Builder
+
+	for _, e := range elems {
+		switch {
+		case e.isIndex:
+			fmt.Fprintf(&sb, "[%d]", e.index)
+		case strings.ContainsAny(e.key, ".[]"):
+			fmt.Fprintf(&sb, "[%q]", e.key)
+		default:
+			sb.WriteString("." + e.key)
+		}
+	}
+
+	return sb.String()
+}
+
+func typeMismatch(elems []pathElement, expected string, value interface{}) error {
+	return fmt.Errorf("%s: expected %s, got %T", formatPath(elems), expected, value)
+}
+
+// Get returns the value of the field at the path. It is not found if the field or
+// a list item is missing and an error is returned if the path goes through a non-container value.
+func (m Manifest) Get(path string) (interface{}, bool, error) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var cur interface{} = map[string]interface{}(m)
+
+	for i, e := range elems {
+		if e.isIndex {
+			list, ok := cur.([]interface{})
+			if !ok {
+				return nil, false, typeMismatch(elems[:i], "list", cur)
+			}
+
+			if e.index >= len(list) {
+				return nil, false, nil
+			}
+
+			cur = list[e.index]
+
+			continue
+		}
+
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false, typeMismatch(elems[:i], "map", cur)
+		}
+
+		val, found := obj[e.key]
+		if !found {
+			return nil, false, nil
+		}
+
+		cur = val
+	}
+
+	return cur, true, nil
+}
+
+// Set sets the field at the path, missing maps are created. List items are not
+// created, so an index must address an existing item.
+// The value should be JSON compatible, as values of manifests decoded from YAML are.
+func (m Manifest) Set(path string, value interface{}) error {
+	elems, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = setPath(map[string]interface{}(m), elems, 0, value)
+
+	return err
+}
+
+func setPath(cur interface{}, elems []pathElement, i int, value interface{}) (interface{}, error) {
+	if i == len(elems) {
+		return value, nil
+	}
+
+	e := elems[i]
+
+	if e.isIndex {
+		list, ok := cur.([]interface{})
+		if !ok {
+			return nil, typeMismatch(elems[:i], "list", cur)
+		}
+
+		if e.index >= len(list) {
+			return nil, fmt.Errorf("%s: index out of range, list has %d items", formatPath(elems[:i+1]), len(list))
+		}
+
+		val, err := setPath(list[e.index], elems, i+1, value)
+		if err != nil {
+			return nil, err
+		}
+
+		list[e.index] = val
+
+		return list, nil
+	}
+
+	if cur == nil {
+		cur = make(map[string]interface{})
+	}
+
+	obj, ok := cur.(map[string]interface{})
+	if !ok {
+		return nil, typeMismatch(elems[:i], "map", cur)
+	}
+
+	val, err := setPath(obj[e.key], elems, i+1, value)
+	if err != nil {
+		return nil, err
+	}
+
+	obj[e.key] = val
+
+	return obj, nil
+}
+
+// Remove removes the field or the list item at the path, it is false if the field is missing
+func (m Manifest) Remove(path string) (bool, error) {
+	elems, err := parsePath(path)
+	if err != nil {
+		return false, err
+	}
+
+	_, removed, err := removePath(map[string]interface{}(m), elems, 0)
+
+	return removed, err
+}
+
+func removePath(cur interface{}, elems []pathElement, i int) (interface{}, bool, error) {
+	e := elems[i]
+	last := i == len(elems)-1
+
+	if e.isIndex {
+		list, ok := cur.([]interface{})
+		if !ok {
+			return nil, false, typeMismatch(elems[:i], "list", cur)
+		}
+
+		if e.index >= len(list) {
+			return list, false, nil
+		}
+
+		if last {
+			res := make([]interface{}, 0, len(list)-1)
+			res = append(res, list[:e.index]...)
+
+			return append(res, list[e.index+1:]...), true, nil
+		}
+
+		val, removed, err := removePath(list[e.index], elems, i+1)
+		if err != nil || !removed {
+			return list, removed, err
+		}
+
+		list[e.index] = val
+
+		return list, true, nil
+	}
+
+	obj, ok := cur.(map[string]interface{})
+	if !ok {
+		return nil, false, typeMismatch(elems[:i], "map", cur)
+	}
+
+	val, found := obj[e.key]
+	if !found {
+		return obj, false, nil
+	}
+
+	if last {
+		delete(obj, e.key)
+		return obj, true, nil
+	}
+
+	val, removed, err := removePath(val, elems, i+1)
+	if err != nil || !removed {
+		return obj, removed, err
+	}
+
+	obj[e.key] = val
+
+	return obj, true, nil
+}
+
+// getTyped returns the value at the path converted by the function, a value of a wrong type is an error
+func getTyped[T any](m Manifest, path, typeName string, convert func(interface{}) (T, bool)) (T, bool, error) {
+	var res T
+
+	val, found, err := m.Get(path)
+	if err != nil || !found {
+		return res, found, err
+	}
+
+	res, ok := convert(val)
+	if !ok {
+		return res, true, fmt.Errorf("%s: expected %s, got %T", path, typeName, val)
+	}
+
+	return res, true, nil
+}
+
+func (m Manifest) GetString(path string) (string, bool, error) {
+	return getTyped(m, path, "string", func(val interface{}) (string, bool) {
+		s, ok := val.(string)
+		return s, ok
+	})
+}
+
+func (m Manifest) GetBool(path string) (bool, bool, error) {
+	return getTyped(m, path, "bool", func(val interface{}) (bool, bool) {
+		b, ok := val.(bool)
+		return b, ok
+	})
+}
+
+// GetInt64 returns an integer field, integral float64 values of manifests decoded from YAML are accepted
+func (m Manifest) GetInt64(path string) (int64, bool, error) {
+	return getTyped(m, path, "integer", func(val interface{}) (int64, bool) {
+		switch v := val.(type) {
+		case int64:
+			return v, true
+		case int:
+			return int64(v), true
+		case int32:
+			return int64(v), true
+		case float64:
+			return int64(v), v == math.Trunc(v)
+		default:
+			return 0, false
+		}
+	})
+}
+
+func (m Manifest) GetFloat64(path string) (float64, bool, error) {
+	return getTyped(m, path, "number", func(val interface{}) (float64, bool) {
+		switch v := val.(type) {
+		case float64:
+			return v, true
+		case int64:
+			return float64(v), true
+		case int:
+			return float64(v), true
+		case int32:
+			return float64(v), true
+		default:
+			return 0, false
+		}
+	})
+}
+
+// GetMap returns a map field, the map is not copied
+func (m Manifest) GetMap(path string) (map[string]interface{}, bool, error) {
+	return getTyped(m, path, "map", func(val interface{}) (map[string]interface{}, bool) {
+		obj, ok := val.(map[string]interface{})
+		return obj, ok
+	})
+}
+
+// GetSlice returns a list field, the list is not copied
+func (m Manifest) GetSlice(path string) ([]interface{}, bool, error) {
+	return getTyped(m, path, "list", func(val interface{}) ([]interface{}, bool) {
+		list, ok := val.([]interface{})
+		return list, ok
+	})
+}
+
+func (m Manifest) GetStringSlice(path string) ([]string, bool, error) {
+	return getTyped(m, path, "list of strings", func(val interface{}) ([]string, bool) {
+		list, ok := val.([]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		res := make([]string, 0, len(list))
+
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+
+			res = append(res, s)
+		}
+
+		return res, true
+	})
+}
+
+func (m Manifest) GetStringMap(path string) (map[string]string, bool, error) {
+	return getTyped(m, path, "map of strings", func(val interface{}) (map[string]string, bool) {
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		res := make(map[string]string, len(obj))
+
+		for k, v := range obj {
+			s, ok := v.(string)
+			if !ok {
+				return nil, false
+			}
+
+			res[k] = s
+		}
+
+		return res, true
+	})
+}
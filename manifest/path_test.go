@@ -0,0 +1,105 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const deploymentYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app.kubernetes.io/name: web
+spec:
+  replicas: 2
+  paused: false
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.25
+        args: ["--port", "8080"]
+      - name: sidecar
+        image: envoy:1.28
+`
+
+func Test_Path_Get(t *testing.T) {
+	g := NewWithT(t)
+	m := MustFromYAML(deploymentYAML)
+
+	val, found, err := m.Get("spec.template.spec.containers[1].name")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(found).Should(BeTrue())
+	g.Expect(val).Should(Equal("sidecar"))
+
+	val, found, err = m.Get(`$.metadata.labels["app.kubernetes.io/name"]`)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(found).Should(BeTrue())
+	g.Expect(val).Should(Equal("web"))
+
+	_, found, err = m.Get(".spec.template.spec.containers[5].name")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(found).Should(BeFalse())
+
+	_, _, err = m.Get("spec.replicas.value")
+	g.Expect(err).Should(MatchError(".spec.replicas: expected map, got float64"))
+
+	_, _, err = m.Get("spec[0")
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func Test_Path_Typed(t *testing.T) {
+	g := NewWithT(t)
+	m := MustFromYAML(deploymentYAML)
+
+	replicas, found, err := m.GetInt64("spec.replicas")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(found).Should(BeTrue())
+	g.Expect(replicas).Should(Equal(int64(2)))
+
+	paused, _, err := m.GetBool("spec.paused")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(paused).Should(BeFalse())
+
+	args, _, err := m.GetStringSlice("spec.template.spec.containers[0].args")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(args).Should(Equal([]string{"--port", "8080"}))
+
+	_, found, err = m.GetString("spec.replicas")
+	g.Expect(found).Should(BeTrue())
+	g.Expect(err).Should(MatchError("spec.replicas: expected string, got float64"))
+
+	_, found, err = m.GetStringMap("spec.selector")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(found).Should(BeFalse())
+}
+
+func Test_Path_Set_Remove(t *testing.T) {
+	g := NewWithT(t)
+	m := MustFromYAML(deploymentYAML)
+
+	g.Expect(m.Set("spec.template.spec.containers[0].image", "nginx:1.26")).Should(Succeed())
+	g.Expect(m.Set("spec.selector.matchLabels['app']", "web")).Should(Succeed())
+	g.Expect(m.Set("spec.template.spec.volumes[0].name", "data")).Should(MatchError(".spec.template.spec.volumes: expected list, got <nil>"))
+	g.Expect(m.Set("spec.template.spec.containers[2].image", "x")).Should(MatchError(ContainSubstring("index out of range")))
+
+	image, _, _ := m.GetString("spec.template.spec.containers[0].image")
+	g.Expect(image).Should(Equal("nginx:1.26"))
+
+	selector, _, _ := m.GetStringMap("spec.selector.matchLabels")
+	g.Expect(selector).Should(Equal(map[string]string{"app": "web"}))
+
+	removed, err := m.Remove("spec.template.spec.containers[0]")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(removed).Should(BeTrue())
+
+	containers, _, _ := m.GetSlice("spec.template.spec.containers")
+	g.Expect(containers).Should(HaveLen(1))
+
+	removed, err = m.Remove("spec.strategy.type")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(removed).Should(BeFalse())
+}
@@ -0,0 +1,101 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// RedactedValue replaces sensitive values
+const RedactedValue = "<redacted>"
+
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// secretPaths are sensitive fields of Secrets, values of maps are redacted one by one
+var secretPaths = []string{"data", "stringData"}
+
+// Redactor masks sensitive values of manifests before they are printed, logged or compared.
+// Values of Secret data and stringData are always masked.
+type Redactor struct {
+	// Hash adds a short SHA-256 of the value to the mask, e.g. "<redacted sha256:9f86d081884c7d65>",
+	// so changes are still detectable. Hashes of short values can be brute-forced.
+	Hash bool
+	// Paths are sensitive fields of other kinds by "Kind.group", e.g. {"Database.example.com": {"spec.password"}}.
+	// Maps and lists are redacted to their leaves.
+	Paths map[string][]string
+}
+
+// Redact returns a copy of the manifest with sensitive values masked. The last-applied-configuration
+// annotation of manifests with sensitive values is masked too, as it has a copy of them.
+func (r Redactor) Redact(m Manifest) Manifest {
+	paths := r.Paths[m.ObjectKey().GroupKind().String()]
+	if m.ApiVersion() == "v1" && m.Kind() == "Secret" {
+		paths = append(append([]string(nil), secretPaths...), paths...)
+	}
+
+	res := m.DeepCopy()
+	if len(paths) == 0 {
+		return res
+	}
+
+	for _, path := range paths {
+		value, found, err := res.Get(path)
+		if err != nil || !found {
+			continue
+		}
+
+		_ = res.Set(path, r.redactValue(value))
+	}
+
+	if annotations, ok := nestedMap(res, "metadata", "annotations"); ok {
+		if value, found := annotations[lastAppliedAnnotation]; found {
+			annotations[lastAppliedAnnotation] = r.redactValue(value)
+		}
+	}
+
+	return res
+}
+
+// RedactList returns a copy of the list with sensitive values masked, see Redact
+func (r Redactor) RedactList(l ManifestList) ManifestList {
+	res := make(ManifestList, 0, len(l))
+	for _, m := range l {
+		res = append(res, r.Redact(m))
+	}
+
+	return res
+}
+
+func (r Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			res[k] = r.redactValue(item)
+		}
+
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			res[i] = r.redactValue(item)
+		}
+
+		return res
+	case nil:
+		return nil
+	}
+
+	if !r.Hash {
+		return RedactedValue
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+
+	return fmt.Sprintf("<redacted sha256:%s>", hex.EncodeToString(sum[:8]))
+}
+
+// Redact returns a copy of the manifest with Secret values masked, see Redactor
+func Redact(m Manifest) Manifest {
+	return Redactor{}.Redact(m)
+}
@@ -0,0 +1,53 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func Test_Redact(t *testing.T) {
+	g := NewWithT(t)
+
+	secret := MustFromYAML(`
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: '{"data":{"password":"c2VjcmV0"}}'
+data:
+  password: c2VjcmV0
+stringData:
+  token: abc
+`)
+
+	redacted := Redact(secret)
+	g.Expect(redacted["data"]).Should(Equal(map[string]interface{}{"password": RedactedValue}))
+	g.Expect(redacted["stringData"]).Should(Equal(map[string]interface{}{"token": RedactedValue}))
+	g.Expect(redacted.Annotations()).Should(HaveKeyWithValue(lastAppliedAnnotation, RedactedValue))
+	g.Expect(secret["data"]).Should(Equal(map[string]interface{}{"password": "c2VjcmV0"}))
+
+	hashed := Redactor{Hash: true}.Redact(secret)
+	g.Expect(hashed["data"]).Should(Equal(map[string]interface{}{"password": "<redacted sha256:1c1185e02ff3e23b>"}))
+	g.Expect(Redactor{Hash: true}.Redact(secret)).Should(Equal(hashed))
+
+	db := MustFromYAML(`
+apiVersion: example.com/v1
+kind: Database
+metadata:
+  name: db
+spec:
+  size: 1
+  auth:
+    users:
+    - name: admin
+      password: secret
+`)
+
+	r := Redactor{Paths: map[string][]string{"Database.example.com": {"spec.auth.users[0].password"}}}
+	list := r.RedactList(ManifestList{db, secret})
+	password, _, _ := list[0].GetString("spec.auth.users[0].password")
+	g.Expect(password).Should(Equal(RedactedValue))
+	g.Expect(list[1]["data"]).Should(Equal(map[string]interface{}{"password": RedactedValue}))
+}
@@ -0,0 +1,477 @@
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/openapi"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// FieldErrorType is a kind of schema violation
+type FieldErrorType string
+
+const (
+	FieldUnknown      FieldErrorType = "unknown field"
+	FieldTypeMismatch FieldErrorType = "type mismatch"
+	FieldRequired     FieldErrorType = "missing required field"
+)
+
+// FieldError is a field of a manifest that doesn't match the schema
+type FieldError struct {
+	Key  ObjectKey
+	Type FieldErrorType
+	// Path is the path of the field, e.g. "spec.template.spec.containers[0].image"
+	Path string
+	// Detail explains type mismatches, e.g. "expected integer, got string"
+	Detail string
+	// Source and Line are set by ValidateDocument, Line is the 1-based line of the field in the stream
+	Source Source
+	Line   int
+}
+
+func (e *FieldError) Error() string {
+	msg := e.Path + ": " + string(e.Type)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+
+	if e.Line == 0 {
+		return e.Key.String() + ": " + msg
+	}
+
+	return (&ParseError{Source: e.Source, Line: e.Line, Err: errors.New(msg)}).Error()
+}
+
+// SchemaValidator validates manifests against OpenAPI v3 schemas served by a cluster,
+// e.g. client.Discovery().OpenAPIV3(), or read from a bundle, see NewOpenAPIBundle.
+// Documents of group versions are fetched once and cached.
+type SchemaValidator struct {
+	client openapi.Client
+
+	mu    sync.Mutex
+	paths map[string]openapi.GroupVersion
+	// schemas are components of OpenAPI documents by API path, e.g. "apis/apps/v1"
+	schemas map[string]map[string]*spec.Schema
+}
+
+func NewSchemaValidator(client openapi.Client) *SchemaValidator {
+	return &SchemaValidator{client: client, schemas: make(map[string]map[string]*spec.Schema)}
+}
+
+// Validate returns fields of the manifest that don't match the schema of its kind.
+// An error is returned if there is no schema for the kind.
+func (v *SchemaValidator) Validate(m Manifest) ([]*FieldError, error) {
+	gvk := schema.FromAPIVersionAndKind(m.ApiVersion(), m.Kind())
+
+	components, root, err := v.schemaFor(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", m.ObjectKey(), err)
+	}
+
+	w := &schemaWalker{components: components, key: m.ObjectKey()}
+	w.validate(nil, map[string]interface{}(m), root)
+
+	sort.SliceStable(w.errs, func(i, j int) bool {
+		return w.errs[i].Path < w.errs[j].Path
+	})
+
+	return w.errs, nil
+}
+
+// ValidateDocument validates the manifest of the document and sets positions of errors
+func (v *SchemaValidator) ValidateDocument(doc *Document) ([]*FieldError, error) {
+	if doc.Manifest == nil {
+		return nil, nil
+	}
+
+	errs, err := v.Validate(doc.Manifest)
+	if err != nil || len(errs) == 0 {
+		return errs, err
+	}
+
+	var root yaml.Node
+
+	// the document is already decoded, so it is valid YAML
+	_ = yaml.Unmarshal(doc.Raw, &root)
+
+	for _, fieldErr := range errs {
+		fieldErr.Source = doc.Source
+		fieldErr.Line = doc.Source.StartLine
+
+		elems, parseErr := parsePath(fieldErr.Path)
+		if parseErr == nil {
+			fieldErr.Line += nodeLine(&root, elems) - 1
+		}
+	}
+
+	return errs, nil
+}
+
+// ValidateList validates all manifests, it stops at the first kind without a schema
+func (v *SchemaValidator) ValidateList(l ManifestList) ([]*FieldError, error) {
+	var res []*FieldError
+
+	for _, m := range l {
+		errs, err := v.Validate(m)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, errs...)
+	}
+
+	return res, nil
+}
+
+// apiPath returns the OpenAPI path of the group version, e.g. "api/v1" or "apis/apps/v1"
+func apiPath(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return "api/" + gv.Version
+	}
+
+	return "apis/" + gv.Group + "/" + gv.Version
+}
+
+func (v *SchemaValidator) schemaFor(gvk schema.GroupVersionKind) (map[string]*spec.Schema, *spec.Schema, error) {
+	components, err := v.components(apiPath(gvk.GroupVersion()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range components {
+		gvks, _ := s.Extensions["x-kubernetes-group-version-kind"].([]interface{})
+		for _, item := range gvks {
+			ext, _ := item.(map[string]interface{})
+			if ext["group"] == gvk.Group && ext["version"] == gvk.Version && ext["kind"] == gvk.Kind {
+				return components, s, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no schema for %s", gvk)
+}
+
+func (v *SchemaValidator) components(path string) (map[string]*spec.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if components, ok := v.schemas[path]; ok {
+		return components, nil
+	}
+
+	if v.paths == nil {
+		paths, err := v.client.Paths()
+		if err != nil {
+			return nil, err
+		}
+
+		v.paths = paths
+	}
+
+	gv, ok := v.paths[path]
+	if !ok {
+		return nil, fmt.Errorf("no OpenAPI schema for %s", path)
+	}
+
+	data, err := gv.Schema("application/json")
+	if err != nil {
+		return nil, fmt.Errorf("reading OpenAPI schema for %s: %w", path, err)
+	}
+
+	var doc struct {
+		Components struct {
+			Schemas map[string]*spec.Schema `json:"schemas"`
+		} `json:"components"`
+	}
+
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("decoding OpenAPI schema for %s: %w", path, err)
+	}
+
+	v.schemas[path] = doc.Components.Schemas
+
+	return doc.Components.Schemas, nil
+}
+
+// schemaWalker collects errors of a manifest
+type schemaWalker struct {
+	components map[string]*spec.Schema
+	key        ObjectKey
+	errs       []*FieldError
+}
+
+func (w *schemaWalker) report(elems []pathElement, typ FieldErrorType, detail string) {
+	w.errs = append(w.errs, &FieldError{Key: w.key, Type: typ, Path: strings.TrimPrefix(formatPath(elems), "."), Detail: detail})
+}
+
+// resolve follows references, a single allOf is used by Kubernetes to add defaults to references
+func (w *schemaWalker) resolve(s *spec.Schema) *spec.Schema {
+	for i := 0; i < 10 && s != nil; i++ {
+		switch {
+		case s.Ref.String() != "":
+			s = w.components[strings.TrimPrefix(s.Ref.String(), "#/components/schemas/")]
+		case len(s.AllOf) == 1 && len(s.Type) == 0 && len(s.Properties) == 0:
+			s = &s.AllOf[0]
+		default:
+			return s
+		}
+	}
+
+	return s
+}
+
+func (w *schemaWalker) validate(elems []pathElement, value interface{}, s *spec.Schema) {
+	s = w.resolve(s)
+	if s == nil || value == nil {
+		return
+	}
+
+	// known fields of objects preserving unknown fields are still validated
+	preserve, _ := s.Extensions.GetBool("x-kubernetes-preserve-unknown-fields")
+	if preserve && len(s.Properties) == 0 {
+		return
+	}
+
+	if intOrString, _ := s.Extensions.GetBool("x-kubernetes-int-or-string"); intOrString {
+		if !matchesType("integer", value) && !matchesType("string", value) {
+			w.report(elems, FieldTypeMismatch, fmt.Sprintf("expected integer or string, got %s", jsonType(value)))
+		}
+
+		return
+	}
+
+	if len(s.Type) == 0 {
+		w.validateAlternatives(elems, value, s, preserve)
+		return
+	}
+
+	for _, typ := range s.Type {
+		if matchesType(typ, value) {
+			w.validateContent(elems, value, s, preserve)
+			return
+		}
+	}
+
+	w.report(elems, FieldTypeMismatch, fmt.Sprintf("expected %s, got %s", strings.Join(s.Type, " or "), jsonType(value)))
+}
+
+// validateAlternatives checks types of oneOf and anyOf, e.g. for Quantity and IntOrString
+func (w *schemaWalker) validateAlternatives(elems []pathElement, value interface{}, s *spec.Schema, preserve bool) {
+	alternatives := append(append([]spec.Schema(nil), s.OneOf...), s.AnyOf...)
+	if len(alternatives) == 0 {
+		if len(s.Properties) > 0 {
+			w.validateContent(elems, value, s, preserve)
+		}
+
+		return
+	}
+
+	var types []string
+
+	for i := range alternatives {
+		alt := w.resolve(&alternatives[i])
+		if alt == nil || len(alt.Type) == 0 {
+			return
+		}
+
+		for _, typ := range alt.Type {
+			if matchesType(typ, value) {
+				w.validateContent(elems, value, alt, preserve)
+				return
+			}
+
+			types = append(types, typ)
+		}
+	}
+
+	w.report(elems, FieldTypeMismatch, fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), jsonType(value)))
+}
+
+// validateContent checks fields of objects and items of lists, unknown fields are allowed if preserve is set
+func (w *schemaWalker) validateContent(elems []pathElement, value interface{}, s *spec.Schema, preserve bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, found := value[name]; !found {
+				w.report(append(elems[:len(elems):len(elems)], pathElement{key: name}), FieldRequired, "")
+			}
+		}
+
+		for _, k := range sortedKeys(value) {
+			child := append(elems[:len(elems):len(elems)], pathElement{key: k})
+
+			if prop, ok := s.Properties[k]; ok {
+				w.validate(child, value[k], &prop)
+				continue
+			}
+
+			switch {
+			case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+				w.validate(child, value[k], s.AdditionalProperties.Schema)
+			case s.AdditionalProperties != nil && s.AdditionalProperties.Allows:
+			case preserve || len(s.Properties) == 0:
+				// objects without properties are free-form, e.g. FieldsV1 or RawExtension
+			default:
+				w.report(child, FieldUnknown, "")
+			}
+		}
+	case []interface{}:
+		if s.Items == nil || s.Items.Schema == nil {
+			return
+		}
+
+		for i, item := range value {
+			w.validate(append(elems[:len(elems):len(elems)], pathElement{index: i, isIndex: true}), item, s.Items.Schema)
+		}
+	}
+}
+
+func matchesType(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		switch v := value.(type) {
+		case int, int32, int64:
+			return true
+		case float64:
+			return v == math.Trunc(v)
+		}
+
+		return false
+	case "number":
+		switch value.(type) {
+		case int, int32, int64, float32, float64:
+			return true
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int32, int64, float32, float64:
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// nodeLine returns the line of the deepest existing node of the path, keys are preferred to values
+func nodeLine(node *yaml.Node, elems []pathElement) int {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := node.Line
+
+	for _, e := range elems {
+		var next *yaml.Node
+
+		switch {
+		case e.isIndex && node.Kind == yaml.SequenceNode && e.index < len(node.Content):
+			next = node.Content[e.index]
+			line = next.Line
+		case !e.isIndex && node.Kind == yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == e.key {
+					next = node.Content[i+1]
+					line = node.Content[i].Line
+
+					break
+				}
+			}
+		}
+
+		if next == nil {
+			return line
+		}
+
+		node = next
+	}
+
+	return line
+}
+
+// openAPIBundle serves OpenAPI v3 documents from files
+type openAPIBundle struct {
+	fsys fs.FS
+}
+
+// NewOpenAPIBundle returns an OpenAPI client reading documents from files named as in
+// api/openapi-spec/v3 of the Kubernetes repository, e.g. "apis__apps__v1_openapi.json"
+// for the apis/apps/v1 path. A bundle can be made with "kubectl get --raw /openapi/v3/apis/apps/v1".
+func NewOpenAPIBundle(fsys fs.FS) openapi.Client {
+	return &openAPIBundle{fsys: fsys}
+}
+
+const bundleFileSuffix = "_openapi.json"
+
+func (b *openAPIBundle) Paths() (map[string]openapi.GroupVersion, error) {
+	entries, err := fs.ReadDir(b.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]openapi.GroupVersion)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), bundleFileSuffix) {
+			continue
+		}
+
+		path := strings.ReplaceAll(strings.TrimSuffix(entry.Name(), bundleFileSuffix), "__", "/")
+		paths[path] = &bundleGroupVersion{fsys: b.fsys, file: entry.Name()}
+	}
+
+	return paths, nil
+}
+
+type bundleGroupVersion struct {
+	fsys fs.FS
+	file string
+}
+
+func (gv *bundleGroupVersion) Schema(contentType string) ([]byte, error) {
+	if contentType != "application/json" {
+		return nil, fmt.Errorf("content type %q is not supported by bundles", contentType)
+	}
+
+	return fs.ReadFile(gv.fsys, gv.file)
+}
+
+func (gv *bundleGroupVersion) ServerRelativeURL() string {
+	return "/openapi/v3/" + strings.ReplaceAll(strings.TrimSuffix(gv.file, bundleFileSuffix), "__", "/")
+}
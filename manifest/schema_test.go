@@ -0,0 +1,119 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/openapi/openapitest"
+)
+
+const invalidDeployment = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replica: 2
+  strategy:
+    rollingUpdate:
+      maxSurge: 25%
+      maxUnavailable: true
+  template:
+    spec:
+      containers:
+      - image: nginx
+        resources:
+          limits:
+            cpu: 1
+            memory: 1Gi
+        ports:
+        - containerPort: "80"
+`
+
+func Test_SchemaValidator(t *testing.T) {
+	g := NewWithT(t)
+
+	v := NewSchemaValidator(openapitest.NewEmbeddedFileClient())
+
+	docs, err := ReadAll(strings.NewReader(invalidDeployment))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	errs, err := v.ValidateDocument(docs[0])
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	var messages []string
+	for _, fieldErr := range errs {
+		messages = append(messages, fieldErr.Error())
+	}
+
+	g.Expect(messages).Should(Equal([]string{
+		"line 9: document 0: spec.replica: unknown field",
+		"line 8: document 0: spec.selector: missing required field",
+		"line 13: document 0: spec.strategy.rollingUpdate.maxUnavailable: type mismatch: expected integer or string, got boolean",
+		"line 17: document 0: spec.template.spec.containers[0].name: missing required field",
+		"line 23: document 0: spec.template.spec.containers[0].ports[0].containerPort: type mismatch: expected integer, got string",
+	}))
+
+	errs, err = v.Validate(New("v1", "ConfigMap", "cm"))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(errs).Should(BeEmpty())
+
+	_, err = v.Validate(New("example.com/v1", "Widget", "w"))
+	g.Expect(err).Should(MatchError("Widget.example.com/w: no OpenAPI schema for apis/example.com/v1"))
+
+	_, err = v.Validate(New("apps/v1", "Widget", "w"))
+	g.Expect(err).Should(MatchError("Widget.apps/w: no schema for apps/v1, Kind=Widget"))
+}
+
+func Test_OpenAPIBundle(t *testing.T) {
+	g := NewWithT(t)
+
+	bundle := NewOpenAPIBundle(fstest.MapFS{
+		"apis__example.com__v1_openapi.json": {Data: []byte(`{"components": {"schemas": {"com.example.v1.Widget": {
+			"type": "object",
+			"x-kubernetes-group-version-kind": [{"group": "example.com", "version": "v1", "kind": "Widget"}],
+			"properties": {"apiVersion": {"type": "string"}, "kind": {"type": "string"}, "metadata": {"type": "object"},
+				"spec": {"type": "object", "required": ["size"], "properties": {"size": {"type": "integer"}}}}
+		}}}}`)},
+		"README.md": {Data: []byte("# bundle")},
+	})
+
+	paths, err := bundle.Paths()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(paths).Should(HaveLen(1))
+	g.Expect(paths["apis/example.com/v1"].ServerRelativeURL()).Should(Equal("/openapi/v3/apis/example.com/v1"))
+
+	widget := New("example.com/v1", "Widget", "w")
+	g.Expect(widget.Set("spec.size", 1.5)).Should(Succeed())
+
+	errs, err := NewSchemaValidator(bundle).Validate(widget)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(errs).Should(HaveLen(1))
+	g.Expect(errs[0].Error()).Should(Equal("Widget.example.com/w: spec.size: type mismatch: expected integer, got number"))
+}
+
+func Test_SchemaValidator_PreserveUnknownFields(t *testing.T) {
+	g := NewWithT(t)
+
+	bundle := NewOpenAPIBundle(fstest.MapFS{
+		"apis__example.com__v1_openapi.json": {Data: []byte(`{"components": {"schemas": {"com.example.v1.Widget": {
+			"type": "object",
+			"x-kubernetes-group-version-kind": [{"group": "example.com", "version": "v1", "kind": "Widget"}],
+			"properties": {"apiVersion": {"type": "string"}, "kind": {"type": "string"}, "metadata": {"type": "object"},
+				"spec": {"type": "object", "x-kubernetes-preserve-unknown-fields": true, "properties": {"size": {"type": "integer"}}}}
+		}}}}`)},
+	})
+
+	widget := New("example.com/v1", "Widget", "w")
+	g.Expect(widget.Set("spec.extra", "value")).Should(Succeed())
+	g.Expect(widget.Set("spec.size", "large")).Should(Succeed())
+
+	errs, err := NewSchemaValidator(bundle).Validate(widget)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(errs).Should(HaveLen(1))
+	g.Expect(errs[0].Error()).Should(Equal("Widget.example.com/w: spec.size: type mismatch: expected integer, got string"))
+}
@@ -0,0 +1,114 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Source is the position of a document in a multi-document stream
+type Source struct {
+	// File is the file the document is read from, it is "-" for stdin and empty for other readers
+	File string
+	// Template is the path from the "# Source: chart/templates/x.yaml" comment of Helm rendered output
+	Template string
+	// Index is the zero-based position of the document in the stream, empty documents are counted
+	Index int
+	// StartLine and EndLine are 1-based lines of the document content, separators are not included
+	StartLine int
+	EndLine   int
+}
+
+func (s Source) String() string {
+	var sb strings.Builder
+
+	if s.File != "" {
+		sb.WriteString(s.File + ", ")
+	}
+
+	fmt.Fprintf(&sb, "document %d (lines %d-%d)", s.Index, s.StartLine, s.EndLine)
+
+	if s.Template != "" {
+		sb.WriteString(", source " + s.Template)
+	}
+
+	return sb.String()
+}
+
+const sourceCommentPrefix = "# Source: "
+
+// sourceTemplate returns the path from the first "# Source:" comment of the document
+func sourceTemplate(raw []byte) string {
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if bytes.HasPrefix(line, []byte(sourceCommentPrefix)) {
+			return strings.TrimSpace(string(line[len(sourceCommentPrefix):]))
+		}
+	}
+
+	return ""
+}
+
+// ParseError is an error of decoding a document with its position in the stream
+type ParseError struct {
+	Source Source
+	// Line is the 1-based line of the stream where the error is found,
+	// it is the first line of the document if the parser doesn't report a line
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	var sb strings.Builder
+
+	if e.Source.File != "" {
+		sb.WriteString(e.Source.File + ":")
+	} else {
+		sb.WriteString("line ")
+	}
+
+	fmt.Fprintf(&sb, "%d: document %d", e.Line, e.Source.Index)
+
+	if e.Source.Template != "" {
+		sb.WriteString(", source " + e.Source.Template)
+	}
+
+	// the position is absolute, so the relative "yaml: line N:" prefix of the parser is dropped
+	msg := e.Err.Error()
+	if match := yamlErrorLine.FindStringIndex(msg); match != nil {
+		msg = msg[match[1]:]
+	}
+
+	sb.WriteString(": " + msg)
+
+	return sb.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var yamlErrorLine = regexp.MustCompile(`yaml: line (\d+): `)
+
+// newParseError makes the error position absolute, YAML parser reports lines relative to the document
+func newParseError(source Source, err error) *ParseError {
+	parseErr := &ParseError{Source: source, Line: source.StartLine, Err: err}
+
+	match := yamlErrorLine.FindStringSubmatchIndex(err.Error())
+	if match == nil {
+		return parseErr
+	}
+
+	msg := err.Error()
+
+	line, convErr := strconv.Atoi(msg[match[2]:match[3]])
+	if convErr != nil {
+		return parseErr
+	}
+
+	parseErr.Line = source.StartLine + line - 1
+
+	return parseErr
+}
@@ -0,0 +1,65 @@
+package manifest
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const helmOutput = `---
+# Source: app/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+    selector: {}
+`
+
+func Test_Source(t *testing.T) {
+	g := NewWithT(t)
+
+	d := NewDecoder(strings.NewReader(helmOutput))
+	d.file = "rendered.yaml"
+
+	doc, err := d.Next()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(doc.Source).Should(Equal(Source{File: "rendered.yaml", Template: "app/templates/configmap.yaml", Index: 0, StartLine: 2, EndLine: 6}))
+	g.Expect(doc.Source.String()).Should(Equal("rendered.yaml, document 0 (lines 2-6), source app/templates/configmap.yaml"))
+
+	_, err = d.Next()
+
+	var parseErr *ParseError
+	g.Expect(errors.As(err, &parseErr)).Should(BeTrue())
+	g.Expect(parseErr.Line).Should(Equal(15))
+	g.Expect(parseErr.Source.Template).Should(Equal("app/templates/deployment.yaml"))
+	g.Expect(err).Should(MatchError("rendered.yaml:15: document 1, source app/templates/deployment.yaml: mapping values are not allowed in this context"))
+
+	_, err = d.Next()
+	g.Expect(errors.Is(err, io.EOF)).Should(BeTrue())
+}
+
+func Test_NewFromYAML_ParseError(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewFromYAML(helmOutput[strings.Index(helmOutput, "---\n# Source: app/templates/deployment.yaml"):])
+
+	var parseErr *ParseError
+	g.Expect(errors.As(err, &parseErr)).Should(BeTrue())
+	g.Expect(err).Should(MatchError("line 9: document 0, source app/templates/deployment.yaml: mapping values are not allowed in this context"))
+	g.Expect(errors.Unwrap(err).Error()).Should(ContainSubstring("yaml: line 8: mapping values are not allowed in this context"))
+
+	m, err := NewFromYAML("# only a comment\n")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(m).Should(BeNil())
+}
@@ -0,0 +1,224 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Substitution replaces placeholders in string values of manifests, keys are never changed.
+// Results are always strings, e.g. "replicas: ${REPLICAS}" is a string after substitution, so use
+// placeholders in fields that are strings, e.g. annotations or ConfigMap data, or convert numeric
+// fields after substitution.
+//
+// Variables are written as in shell:
+//
+//	${VAR}          the value of VAR, the placeholder is kept if VAR is undefined
+//	${VAR:-default} the default if VAR is undefined or empty
+//	${VAR-default}  the default if VAR is undefined
+//	${VAR:?message} an error if VAR is undefined or empty
+//	${VAR?message}  an error if VAR is undefined
+//	$${VAR}         the literal "${VAR}", other "$$" are kept as is, e.g. "kill $$"
+//
+// Values are written as a subset of Helm templates:
+//
+//	{{ .Values.image.tag }}
+//	{{ .Values.image.tag | default "latest" }}
+//
+// Both are replaced in one pass, so substituted values are never substituted again.
+type Substitution struct {
+	Vars   map[string]string
+	Values map[string]interface{}
+}
+
+// SubstitutionReport lists variables and values by usage, values are named as ".Values.a.b"
+type SubstitutionReport struct {
+	// Used are variables and values found in manifests and defined
+	Used []string
+	// Unused are defined variables and values that are not found in manifests
+	Unused []string
+	// Undefined are variables and values found in manifests without a definition or a default
+	Undefined []string
+}
+
+// RequiredVariableError is returned for ${VAR:?message} placeholders of undefined variables
+type RequiredVariableError struct {
+	Key     ObjectKey
+	Path    string
+	Name    string
+	Message string
+}
+
+func (e *RequiredVariableError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = "required variable is not set"
+	}
+
+	return fmt.Sprintf("%s: %s: %s: %s", e.Key, e.Path, e.Name, msg)
+}
+
+// placeholderPattern matches the "$${" escape, variables and values in one pass,
+// so substituted text is never scanned again
+var placeholderPattern = regexp.MustCompile(`\$\$\{` +
+	`|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?[-?])([^}]*))?\}` +
+	`|\{\{-?\s*\.Values((?:\.[A-Za-z0-9_]+)+)\s*(?:\|\s*default\s+("(?:[^"\\]|\\.)*")\s*)?-?\}\}`)
+
+// Apply substitutes placeholders in the manifests in place. All required variable errors are joined.
+func (s Substitution) Apply(l ManifestList) (*SubstitutionReport, error) {
+	st := &substitutionState{Substitution: s, used: make(map[string]bool), undefined: make(map[string]bool)}
+
+	for _, m := range l {
+		st.key = m.ObjectKey()
+		st.walk(nil, map[string]interface{}(m))
+	}
+
+	return st.report(), errors.Join(st.errs...)
+}
+
+type substitutionState struct {
+	Substitution
+
+	key       ObjectKey
+	used      map[string]bool
+	undefined map[string]bool
+	errs      []error
+}
+
+func (st *substitutionState) walk(elems []pathElement, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = st.walk(append(elems[:len(elems):len(elems)], pathElement{key: k}), item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = st.walk(append(elems[:len(elems):len(elems)], pathElement{index: i, isIndex: true}), item)
+		}
+	case string:
+		return st.substitute(elems, v)
+	}
+
+	return value
+}
+
+func (st *substitutionState) substitute(elems []pathElement, s string) string {
+	return placeholderPattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		groups := placeholderPattern.FindStringSubmatch(match)
+		if groups[4] != "" {
+			return st.value(groups[4], groups[5], match)
+		}
+
+		return st.variable(elems, groups[1], groups[2], groups[3], match)
+	})
+}
+
+// value returns the value at the path, e.g. ".image.tag", or the quoted default
+func (st *substitutionState) value(path, quotedDefault, match string) string {
+	name := ".Values" + path
+
+	value, found, err := Manifest(st.Values).Get(strings.TrimPrefix(path, "."))
+	if err == nil && found && value != nil {
+		st.used[name] = true
+		return fmt.Sprint(value)
+	}
+
+	if quotedDefault != "" {
+		def, err := strconv.Unquote(quotedDefault)
+		if err == nil {
+			return def
+		}
+	}
+
+	st.undefined[name] = true
+
+	return match
+}
+
+// variable returns the value of the variable according to the operator, see Substitution
+func (st *substitutionState) variable(elems []pathElement, name, op, arg, match string) string {
+	value, defined := st.Vars[name]
+	if defined {
+		st.used[name] = true
+	}
+
+	// with a colon, empty values are treated as undefined
+	set := defined && (value != "" || !strings.HasPrefix(op, ":"))
+
+	switch strings.TrimPrefix(op, ":") {
+	case "-":
+		if set {
+			return value
+		}
+
+		return arg
+	case "?":
+		if set {
+			return value
+		}
+
+		st.errs = append(st.errs, &RequiredVariableError{
+			Key:     st.key,
+			Path:    strings.TrimPrefix(formatPath(elems), "."),
+			Name:    name,
+			Message: arg,
+		})
+
+		return match
+	}
+
+	if !defined {
+		st.undefined[name] = true
+		return match
+	}
+
+	return value
+}
+
+func (st *substitutionState) report() *SubstitutionReport {
+	defined := make(map[string]bool)
+	for name := range st.Vars {
+		defined[name] = true
+	}
+
+	valueLeaves(".Values", st.Values, defined)
+
+	report := &SubstitutionReport{}
+
+	for name := range defined {
+		if st.used[name] {
+			report.Used = append(report.Used, name)
+		} else {
+			report.Unused = append(report.Unused, name)
+		}
+	}
+
+	for name := range st.undefined {
+		report.Undefined = append(report.Undefined, name)
+	}
+
+	sort.Strings(report.Used)
+	sort.Strings(report.Unused)
+	sort.Strings(report.Undefined)
+
+	return report
+}
+
+// valueLeaves adds paths of values that are not maps
+func valueLeaves(prefix string, values map[string]interface{}, res map[string]bool) {
+	for k, v := range values {
+		if nested, ok := v.(map[string]interface{}); ok {
+			valueLeaves(prefix+"."+k, nested, res)
+			continue
+		}
+
+		res[prefix+"."+k] = true
+	}
+}
@@ -0,0 +1,123 @@
+package manifest
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const substituteYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: ${NAMESPACE}
+  annotations:
+    ${NAMESPACE}: key
+    replicas: ${REPLICAS:-1}
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "{{ .Values.image.repository }}:{{ .Values.image.tag | default \"latest\" }}"
+        args:
+        - --cost=$${PRICE}
+        - --region=${REGION}
+        - --debug=${DEBUG-false}
+        - --mode=${MODE:-safe}
+        - --owner={{ .Values.owner }}
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+stringData:
+  token: ${TOKEN:?set the API token}
+`
+
+func Test_Substitution(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(substituteYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	list := ManifestList(manifests)
+
+	s := Substitution{
+		Vars: map[string]string{"NAMESPACE": "prod", "MODE": "", "DEBUG": "", "UNUSED": "x"},
+		Values: map[string]interface{}{
+			"image":    map[string]interface{}{"repository": "nginx"},
+			"replicas": 3,
+		},
+	}
+
+	report, err := s.Apply(list)
+
+	var requiredErr *RequiredVariableError
+	g.Expect(errors.As(err, &requiredErr)).Should(BeTrue())
+	g.Expect(err).Should(MatchError("Secret/creds: stringData.token: TOKEN: set the API token"))
+
+	g.Expect(report).Should(Equal(&SubstitutionReport{
+		Used:      []string{".Values.image.repository", "DEBUG", "MODE", "NAMESPACE"},
+		Unused:    []string{".Values.replicas", "UNUSED"},
+		Undefined: []string{".Values.owner", "REGION"},
+	}))
+
+	deploy := list[0]
+	g.Expect(deploy.Unstructured().GetNamespace()).Should(Equal("prod"))
+	g.Expect(deploy.Annotations()).Should(HaveKey("${NAMESPACE}"))
+
+	g.Expect(deploy.Annotations()).Should(HaveKeyWithValue("replicas", "1"))
+
+	image, _, _ := deploy.GetString("spec.template.spec.containers[0].image")
+	g.Expect(image).Should(Equal("nginx:latest"))
+
+	args, _, _ := deploy.GetStringSlice("spec.template.spec.containers[0].args")
+	g.Expect(args).Should(Equal([]string{
+		"--cost=${PRICE}",
+		"--region=${REGION}",
+		"--debug=",
+		"--mode=safe",
+		"--owner={{ .Values.owner }}",
+	}))
+
+	s.Vars["TOKEN"] = "abc"
+	list = ManifestList{list[1]}
+	_, err = s.Apply(list)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(list[0]["stringData"]).Should(Equal(map[string]interface{}{"token": "abc"}))
+}
+
+func Test_Substitution_SinglePass(t *testing.T) {
+	g := NewWithT(t)
+
+	m, err := NewFromYAML(`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: scripts
+data:
+  stop: kill $$; echo ok
+  password: "{{ .Values.password }}"
+  template: "{{ .Values.template }}"
+  escaped: $${X}-${X}
+`)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	s := Substitution{
+		Vars:   map[string]string{"X": "x"},
+		Values: map[string]interface{}{"password": "pa$${X}", "template": "${X}"},
+	}
+
+	_, err = s.Apply(ManifestList{m})
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	g.Expect(m["data"]).Should(Equal(map[string]interface{}{
+		"stop":     "kill $$; echo ok",
+		"password": "pa$${X}",
+		"template": "${X}",
+		"escaped":  "${X}-x",
+	}))
+}
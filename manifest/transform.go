@@ -0,0 +1,237 @@
+package manifest
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Transformer changes manifests of the list in place
+type Transformer func(l ManifestList) error
+
+// Transform runs transformers one by one, it stops at the first error
+func (l ManifestList) Transform(transformers ...Transformer) error {
+	for _, transform := range transformers {
+		err := transform(l)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Pipeline combines transformers into one
+func Pipeline(transformers ...Transformer) Transformer {
+	return func(l ManifestList) error {
+		return l.Transform(transformers...)
+	}
+}
+
+// selectorPaths are label selectors of workloads matching pods by labels, Service and
+// ReplicationController selectors are changed only if they are set, other selectors are created
+var selectorPaths = map[schema.GroupKind][]string{
+	{Group: "apps", Kind: "Deployment"}:       {"spec", "selector", "matchLabels"},
+	{Group: "apps", Kind: "ReplicaSet"}:       {"spec", "selector", "matchLabels"},
+	{Group: "apps", Kind: "StatefulSet"}:      {"spec", "selector", "matchLabels"},
+	{Group: "apps", Kind: "DaemonSet"}:        {"spec", "selector", "matchLabels"},
+	{Group: "extensions", Kind: "Deployment"}: {"spec", "selector", "matchLabels"},
+	{Group: "extensions", Kind: "ReplicaSet"}: {"spec", "selector", "matchLabels"},
+	{Group: "extensions", Kind: "DaemonSet"}:  {"spec", "selector", "matchLabels"},
+	{Kind: "ReplicationController"}:           {"spec", "selector"},
+	{Kind: "Service"}:                         {"spec", "selector"},
+}
+
+// optionalSelectorKinds have selectors that are changed only if they are set
+var optionalSelectorKinds = map[schema.GroupKind]bool{
+	{Kind: "ReplicationController"}: true,
+	{Kind: "Service"}:               true,
+}
+
+var podKind = schema.GroupKind{Kind: "Pod"}
+
+// CommonLabels adds labels to all manifests, their pod templates and selectors.
+// Selectors of existing workloads are immutable, so changing common labels requires recreation.
+func CommonLabels(labels map[string]string) Transformer {
+	return func(l ManifestList) error {
+		for _, m := range l {
+			mergeStrings(m, labels, "metadata", "labels")
+
+			gk := m.ObjectKey().GroupKind()
+
+			if template, ok := m.podTemplate(); ok && gk != podKind {
+				mergeStrings(template, labels, "metadata", "labels")
+			}
+
+			path, ok := selectorPaths[gk]
+			if !ok {
+				continue
+			}
+
+			if optionalSelectorKinds[gk] {
+				if _, found := nestedMap(m, path...); !found {
+					continue
+				}
+			}
+
+			mergeStrings(m, labels, path...)
+		}
+
+		return nil
+	}
+}
+
+// CommonAnnotations adds annotations to all manifests and their pod templates
+func CommonAnnotations(annotations map[string]string) Transformer {
+	return func(l ManifestList) error {
+		for _, m := range l {
+			mergeStrings(m, annotations, "metadata", "annotations")
+
+			if template, ok := m.podTemplate(); ok && m.ObjectKey().GroupKind() != podKind {
+				mergeStrings(template, annotations, "metadata", "annotations")
+			}
+		}
+
+		return nil
+	}
+}
+
+// fixedNameKinds have names that can't be changed, e.g. a CRD name is made of its plural and group
+var fixedNameKinds = map[schema.GroupKind]bool{
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:             true,
+	{Kind: "Namespace"}: true,
+}
+
+// NamePrefixSuffix adds the prefix and the suffix to names of manifests and fixes references
+// to renamed objects: pod specs, role bindings, owner references and Ingress backends, see BuildGraph.
+func NamePrefixSuffix(prefix, suffix string) Transformer {
+	return func(l ManifestList) error {
+		renamed := make(map[schemaKey]string)
+
+		for _, m := range l {
+			if fixedNameKinds[m.ObjectKey().GroupKind()] || m.Name() == "" {
+				continue
+			}
+
+			key := m.ObjectKey()
+			name := prefix + key.Name + suffix
+			renamed[schemaKey{key.GroupKind(), key.Namespace, key.Name}] = name
+			m.SetName(name)
+		}
+
+		for _, m := range l {
+			for _, ref := range manifestRefs(m) {
+				name, found := renamed[schemaKey{ref.To.GroupKind(), ref.To.Namespace, ref.To.Name}]
+				if !found {
+					// owners of namespaced objects can be cluster-scoped
+					name, found = renamed[schemaKey{ref.To.GroupKind(), "", ref.To.Name}]
+				}
+
+				if !found {
+					continue
+				}
+
+				if err := m.Set(ref.Path, name); err != nil {
+					return fmt.Errorf("%s: %w", m.ObjectKey(), err)
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// bindingKinds have subjects that refer to ServiceAccounts in other namespaces
+var bindingKinds = map[schema.GroupKind]bool{
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:        true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: true,
+}
+
+var serviceAccountKind = schema.GroupKind{Kind: "ServiceAccount"}
+
+// Namespace moves namespaced manifests to the namespace and removes the namespace of
+// cluster-scoped ones, see DefaultNamespace. ServiceAccount subjects of role bindings
+// are moved too if the ServiceAccount is in the list.
+func Namespace(ns string, resolver ScopeResolver) Transformer {
+	return func(l ManifestList) error {
+		serviceAccounts := make(map[[2]string]bool)
+
+		for _, m := range l {
+			if m.ObjectKey().GroupKind() == serviceAccountKind {
+				serviceAccounts[[2]string{m.Unstructured().GetNamespace(), m.Name()}] = true
+			}
+		}
+
+		for _, m := range l {
+			if m.Unstructured().GetNamespace() != "" {
+				delete(m.Metadata(), "namespace")
+			}
+
+			err := DefaultNamespace(m, ns, resolver)
+			if err != nil {
+				return err
+			}
+
+			if !bindingKinds[m.ObjectKey().GroupKind()] {
+				continue
+			}
+
+			for _, subject := range nestedMaps(m, "subjects") {
+				key := [2]string{getFieldString(subject, "namespace"), getFieldString(subject, "name")}
+				if getFieldString(subject, "kind") == "ServiceAccount" && serviceAccounts[key] {
+					subject["namespace"] = ns
+				}
+			}
+		}
+
+		return nil
+	}
+}
+
+// ImageOverride changes images with the name, e.g. "nginx" or "registry.example.com/app"
+type ImageOverride struct {
+	Name string
+	// NewName replaces the name, the tag and the digest are kept
+	NewName string
+	// NewTag replaces the tag and removes the digest
+	NewTag string
+	// Digest replaces the tag and the digest, e.g. "sha256:..."
+	Digest string
+}
+
+func (o ImageOverride) apply(image string) (string, bool) {
+	name, tag, digest := splitImage(image)
+	if name != o.Name {
+		return image, false
+	}
+
+	if o.NewName != "" {
+		name = o.NewName
+	}
+
+	if o.NewTag != "" {
+		tag, digest = o.NewTag, ""
+	}
+
+	if o.Digest != "" {
+		tag, digest = "", o.Digest
+	}
+
+	return joinImage(name, tag, digest), true
+}
+
+// Images changes images of containers in pod templates, the first matching override is used
+func Images(overrides ...ImageOverride) Transformer {
+	return func(l ManifestList) error {
+		return ImageScanner{}.Rewrite(l, func(ref ImageRef) (string, error) {
+			for _, o := range overrides {
+				if image, changed := o.apply(ref.Image); changed {
+					return image, nil
+				}
+			}
+
+			return ref.Image, nil
+		})
+	}
+}
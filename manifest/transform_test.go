@@ -0,0 +1,191 @@
+package manifest
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+const transformYAML = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: dev
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      serviceAccountName: web
+      containers:
+      - name: app
+        image: registry.example.com:5000/app:1.0
+        envFrom:
+        - configMapRef:
+            name: web-config
+        - secretRef:
+            name: external
+      volumes:
+      - name: tls
+        secret:
+          secretName: web-tls
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: dev
+spec:
+  selector:
+    app: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web-config
+  namespace: dev
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web-tls
+  namespace: dev
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  namespace: dev
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: web
+subjects:
+- kind: ServiceAccount
+  name: web
+  namespace: dev
+- kind: ServiceAccount
+  name: other
+  namespace: dev
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: web
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: web
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+  namespace: dev
+spec:
+  defaultBackend:
+    service:
+      name: web
+  tls:
+  - secretName: web-tls
+`
+
+func Test_Transform(t *testing.T) {
+	g := NewWithT(t)
+
+	manifests, err := ListFromYamlDocs(transformYAML)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	list := ManifestList(manifests)
+
+	err = list.Transform(
+		Pipeline(
+			CommonLabels(map[string]string{"team": "core"}),
+			CommonAnnotations(map[string]string{"owner": "core@example.com"}),
+		),
+		Namespace("prod", NewStaticResolver(append(append(testResources, rbacResources...), networkingResources...))),
+		Images(ImageOverride{Name: "registry.example.com:5000/app", NewName: "mirror.local/app", NewTag: "1.1"}),
+		NamePrefixSuffix("team-", ""),
+	)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	str := func(m Manifest, path string) string {
+		v, _, err := m.GetString(path)
+		g.Expect(err).ShouldNot(HaveOccurred())
+
+		return v
+	}
+	stringMap := func(m Manifest, path string) map[string]string {
+		v, _, err := m.GetStringMap(path)
+		g.Expect(err).ShouldNot(HaveOccurred())
+
+		return v
+	}
+
+	deploy := list[0]
+	g.Expect(deploy.ObjectKey().String()).Should(Equal("Deployment.apps/prod/team-web"))
+	g.Expect(stringMap(deploy, "spec.selector.matchLabels")).Should(Equal(map[string]string{"app": "web", "team": "core"}))
+	g.Expect(stringMap(deploy, "spec.template.metadata.labels")).Should(Equal(map[string]string{"app": "web", "team": "core"}))
+	g.Expect(stringMap(deploy, "spec.template.metadata.annotations")).Should(Equal(map[string]string{"owner": "core@example.com"}))
+	g.Expect(str(deploy, "spec.template.spec.containers[0].image")).Should(Equal("mirror.local/app:1.1"))
+	g.Expect(str(deploy, "spec.template.spec.serviceAccountName")).Should(Equal("team-web"))
+	g.Expect(str(deploy, "spec.template.spec.containers[0].envFrom[0].configMapRef.name")).Should(Equal("team-web-config"))
+	g.Expect(str(deploy, "spec.template.spec.containers[0].envFrom[1].secretRef.name")).Should(Equal("external"))
+	g.Expect(str(deploy, "spec.template.spec.volumes[0].secret.secretName")).Should(Equal("team-web-tls"))
+
+	g.Expect(stringMap(list[1], "spec.selector")).Should(Equal(map[string]string{"app": "web", "team": "core"}))
+
+	binding := list[5]
+	g.Expect(binding.ObjectKey().String()).Should(Equal("ClusterRoleBinding.rbac.authorization.k8s.io/team-web"))
+	g.Expect(str(binding, "subjects[0].namespace")).Should(Equal("prod"))
+	g.Expect(str(binding, "subjects[1].namespace")).Should(Equal("dev"))
+	g.Expect(str(binding, "subjects[0].name")).Should(Equal("team-web"))
+	g.Expect(str(binding, "subjects[1].name")).Should(Equal("other"))
+	g.Expect(str(binding, "roleRef.name")).Should(Equal("team-web"))
+
+	ingress := list[7]
+	g.Expect(str(ingress, "spec.defaultBackend.service.name")).Should(Equal("team-web"))
+	g.Expect(str(ingress, "spec.tls[0].secretName")).Should(Equal("team-web-tls"))
+
+	missing := BuildGraph(list).Missing()
+	g.Expect(missing).Should(HaveLen(2))
+	g.Expect(missing[0].To.String()).Should(Equal("Secret/prod/external"))
+	g.Expect(missing[1].To.String()).Should(Equal("ServiceAccount/dev/other"))
+}
+
+func Test_Transform_CustomKindsNamedLikeBuiltins(t *testing.T) {
+	g := NewWithT(t)
+
+	m, err := NewFromYAML(`
+apiVersion: example.com/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    spec:
+      serviceAccountName: web
+`)
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	list := ManifestList{m, New("v1", "ServiceAccount", "web")}
+
+	g.Expect(list.Transform(CommonLabels(map[string]string{"team": "core"}), NamePrefixSuffix("team-", ""))).Should(Succeed())
+
+	selector, _, _ := m.GetStringMap("spec.selector.matchLabels")
+	g.Expect(selector).Should(Equal(map[string]string{"app": "web"}))
+
+	sa, _, _ := m.GetString("spec.template.spec.serviceAccountName")
+	g.Expect(sa).Should(Equal("web"))
+
+	g.Expect(BuildGraph(list).References).Should(BeEmpty())
+}
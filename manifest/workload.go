@@ -0,0 +1,196 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// podTemplatePaths are paths to pod templates of built-in workloads, a Pod is a template itself.
+// Kinds are group-qualified, so custom resources named like built-ins are not matched.
+var podTemplatePaths = map[schema.GroupKind][]string{
+	{Kind: "Pod"}:                             {},
+	{Kind: "ReplicationController"}:           {"spec", "template"},
+	{Group: "apps", Kind: "Deployment"}:       {"spec", "template"},
+	{Group: "apps", Kind: "ReplicaSet"}:       {"spec", "template"},
+	{Group: "apps", Kind: "StatefulSet"}:      {"spec", "template"},
+	{Group: "apps", Kind: "DaemonSet"}:        {"spec", "template"},
+	{Group: "extensions", Kind: "Deployment"}: {"spec", "template"},
+	{Group: "extensions", Kind: "ReplicaSet"}: {"spec", "template"},
+	{Group: "extensions", Kind: "DaemonSet"}:  {"spec", "template"},
+	{Group: "batch", Kind: "Job"}:             {"spec", "template"},
+	{Group: "batch", Kind: "CronJob"}:         {"spec", "jobTemplate", "spec", "template"},
+}
+
+// podTemplatePath returns the path to the pod template of a built-in workload
+func (m Manifest) podTemplatePath() ([]string, bool) {
+	path, ok := podTemplatePaths[m.ObjectKey().GroupKind()]
+	return path, ok
+}
+
+// podTemplate returns the pod template of a workload or the Pod itself, the result is not a copy
+func (m Manifest) podTemplate() (map[string]interface{}, bool) {
+	path, ok := m.podTemplatePath()
+	if !ok {
+		return nil, false
+	}
+
+	return nestedMap(m, path...)
+}
+
+// containerFields are lists of containers in a pod spec
+var containerFields = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// forEachContainer calls fn for every container of the pod spec,
+// field is the list of the container, e.g. "initContainers", and i is the index in the list
+func forEachContainer(podSpec map[string]interface{}, fn func(field string, i int, container map[string]interface{})) {
+	for _, field := range containerFields {
+		containers, _ := podSpec[field].([]interface{})
+		for i, c := range containers {
+			if container, ok := c.(map[string]interface{}); ok {
+				fn(field, i, container)
+			}
+		}
+	}
+}
+
+// nestedMap returns the map at the path, the result is not a copy
+func nestedMap(obj map[string]interface{}, fields ...string) (map[string]interface{}, bool) {
+	cur := obj
+
+	for _, field := range fields {
+		next, ok := cur[field].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		cur = next
+	}
+
+	return cur, true
+}
+
+// ensureMap returns the map at the path, missing maps are created
+func ensureMap(obj map[string]interface{}, fields ...string) map[string]interface{} {
+	cur := obj
+
+	for _, field := range fields {
+		next, ok := cur[field].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			cur[field] = next
+		}
+
+		cur = next
+	}
+
+	return cur
+}
+
+// mergeStrings sets values in the map at the path, missing maps are created
+func mergeStrings(obj map[string]interface{}, values map[string]string, fields ...string) {
+	if len(values) == 0 {
+		return
+	}
+
+	target := ensureMap(obj, fields...)
+	for k, v := range values {
+		target[k] = v
+	}
+}
+
+// nestedMaps returns maps of the list at the path, other items are skipped
+func nestedMaps(obj map[string]interface{}, fields ...string) []map[string]interface{} {
+	parent, ok := nestedMap(obj, fields[:len(fields)-1]...)
+	if !ok {
+		return nil
+	}
+
+	items, _ := parent[fields[len(fields)-1]].([]interface{})
+
+	var res []map[string]interface{}
+
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			res = append(res, m)
+		}
+	}
+
+	return res
+}
+
+// objectRef is a reference by name from a pod spec, obj[field] holds the name of an object of the kind
+type objectRef struct {
+	kind  string
+	obj   map[string]interface{}
+	field string
+	// path is the path of the name in the pod spec, e.g. "volumes[0].configMap.name"
+	path string
+}
+
+func (r objectRef) name() string {
+	return getFieldString(r.obj, r.field)
+}
+
+// optional is true for references with "optional: true", e.g. configMap volumes
+func (r objectRef) optional() bool {
+	optional, _ := r.obj["optional"].(bool)
+	return optional
+}
+
+// podSpecRefs returns references of the pod spec to ConfigMaps, Secrets,
+// PersistentVolumeClaims and the ServiceAccount in the namespace of the pod
+func podSpecRefs(podSpec map[string]interface{}) []objectRef {
+	var refs []objectRef
+
+	add := func(kind string, obj map[string]interface{}, prefix string, fields ...string) {
+		parent, ok := nestedMap(obj, fields[:len(fields)-1]...)
+		field := fields[len(fields)-1]
+
+		if ok && getFieldString(parent, field) != "" {
+			refs = append(refs, objectRef{kind: kind, obj: parent, field: field, path: prefix + strings.Join(fields, ".")})
+		}
+	}
+
+	add("ServiceAccount", podSpec, "", "serviceAccountName")
+
+	for i, secret := range nestedMaps(podSpec, "imagePullSecrets") {
+		add("Secret", secret, fmt.Sprintf("imagePullSecrets[%d].", i), "name")
+	}
+
+	for i, volume := range nestedMaps(podSpec, "volumes") {
+		prefix := fmt.Sprintf("volumes[%d].", i)
+
+		add("ConfigMap", volume, prefix, "configMap", "name")
+		add("Secret", volume, prefix, "secret", "secretName")
+		add("PersistentVolumeClaim", volume, prefix, "persistentVolumeClaim", "claimName")
+
+		for j, source := range nestedMaps(volume, "projected", "sources") {
+			sourcePrefix := fmt.Sprintf("%sprojected.sources[%d].", prefix, j)
+
+			add("ConfigMap", source, sourcePrefix, "configMap", "name")
+			add("Secret", source, sourcePrefix, "secret", "name")
+		}
+	}
+
+	forEachContainer(podSpec, func(field string, i int, container map[string]interface{}) {
+		prefix := fmt.Sprintf("%s[%d].", field, i)
+
+		for j, env := range nestedMaps(container, "env") {
+			envPrefix := fmt.Sprintf("%senv[%d].", prefix, j)
+
+			add("ConfigMap", env, envPrefix, "valueFrom", "configMapKeyRef", "name")
+			add("Secret", env, envPrefix, "valueFrom", "secretKeyRef", "name")
+		}
+
+		for j, envFrom := range nestedMaps(container, "envFrom") {
+			envFromPrefix := fmt.Sprintf("%senvFrom[%d].", prefix, j)
+
+			add("ConfigMap", envFrom, envFromPrefix, "configMapRef", "name")
+			add("Secret", envFrom, envFromPrefix, "secretRef", "name")
+		}
+	})
+
+	return refs
+}